@@ -3,9 +3,15 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
-	"godemo/internal/api"
 	"github.com/joho/godotenv"
+	"godemo/internal/api"
+	"godemo/internal/audio"
+	"godemo/internal/jobs"
+	"godemo/internal/render"
 )
 
 func main() {
@@ -18,11 +24,29 @@ func main() {
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/process-recording", api.ProcessRecording)
+	mux.HandleFunc("GET /sessions/{id}/events", api.SessionEvents)
+
+	// Asynchronous job API backed by a worker pool
+	jobManager := jobs.NewManager(envInt("JOB_WORKERS", 2), envInt("JOB_QUEUE_SIZE", 64), time.Hour)
+	api.RegisterJobRoutes(mux, jobManager)
 
 	// Serve generated audio files
-	fs := http.FileServer(http.Dir("instructions/temp_audio"))
+	fs := http.FileServer(http.Dir(audio.Dir))
 	mux.Handle("/audio/", http.StripPrefix("/audio/", fs))
 
+	// Serve rendered MP4s
+	renders := http.FileServer(http.Dir(render.Dir))
+	mux.Handle("/renders/", http.StripPrefix("/renders/", renders))
+
+	// Serve generated SRT/WebVTT captions
+	mux.HandleFunc("GET /captions/{file}", api.ServeCaptions)
+
+	// Narration prompt presets, editable at runtime
+	mux.HandleFunc("GET /presets", api.ListPresets)
+	mux.HandleFunc("GET /presets/{name}", api.GetPreset)
+	mux.HandleFunc("PUT /presets/{name}", api.PutPreset)
+	mux.HandleFunc("DELETE /presets/{name}", api.DeletePreset)
+
 	server := &http.Server{
 		Addr:    ":8000",
 		Handler: mux,
@@ -31,3 +55,11 @@ func main() {
 	log.Println("[INFO] Go narration engine running on :8000")
 	log.Fatal(server.ListenAndServe())
 }
+
+// envInt reads a positive integer from the environment, falling back to def
+func envInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
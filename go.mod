@@ -2,4 +2,6 @@ module godemo
 
 go 1.25
 
-require github.com/joho/godotenv v1.5.1 // indirect
+require github.com/joho/godotenv v1.5.1
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"path/filepath"
+
+	"godemo/internal/captions"
+)
+
+// ServeCaptions serves /captions/{session}.srt and /captions/{session}.vtt
+func ServeCaptions(w http.ResponseWriter, r *http.Request) {
+	file := filepath.Base(r.PathValue("file"))
+
+	switch filepath.Ext(file) {
+	case ".srt":
+		w.Header().Set("Content-Type", "application/x-subrip; charset=utf-8")
+	case ".vtt":
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	default:
+		http.Error(w, "captions are available as .srt or .vtt", http.StatusNotFound)
+		return
+	}
+
+	http.ServeFile(w, r, filepath.Join(captions.Dir, file))
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"godemo/internal/jobs"
+	"godemo/internal/progress"
+)
+
+// sessionStreams holds progress for synchronous /process-recording calls, keyed by session ID
+var sessionStreams = progress.NewRegistry(5 * time.Minute)
+
+// SessionEvents streams pipeline progress for a synchronous request as Server-Sent Events
+func SessionEvents(w http.ResponseWriter, r *http.Request) {
+	stream, ok := sessionStreams.Lookup(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	serveEvents(w, r, stream)
+}
+
+func jobEvents(m *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := m.Get(r.PathValue("id"))
+		if !ok {
+			http.Error(w, jobs.ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		serveEvents(w, r, job.Events())
+	}
+}
+
+// serveEvents replays the stream history, then forwards live events until the
+// stream closes or the client goes away. Last-Event-ID resumes after a reconnect.
+func serveEvents(w http.ResponseWriter, r *http.Request, stream *progress.Stream) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	lastSeq, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	history, live, cancel := stream.Subscribe()
+	defer cancel()
+
+	for _, ev := range history {
+		if ev.Seq > lastSeq {
+			writeEvent(w, ev)
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case ev, ok := <-live:
+			if !ok {
+				return
+			}
+			writeEvent(w, ev)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent sends one SSE event. Events outside the history (Seq 0) carry no
+// id, so a reconnecting client's Last-Event-ID stays on the last real event.
+func writeEvent(w http.ResponseWriter, ev progress.Event) {
+	data, _ := json.Marshal(ev)
+	if ev.Seq > 0 {
+		fmt.Fprintf(w, "id: %d\n", ev.Seq)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+}
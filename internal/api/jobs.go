@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"godemo/internal/jobs"
+	"godemo/internal/models"
+)
+
+// Job types handled by the worker pool
+const (
+	JobTypeProcess = "process-recording"
+	JobTypeRender  = "render"
+)
+
+// RegisterJobRoutes wires the asynchronous job endpoints and handlers onto mux.
+//
+//	POST   /jobs              submit a recording (same body as /process-recording)
+//	POST   /jobs/render       render the MP4 for a finished recording job
+//	GET    /jobs/{id}         poll state, stage and final payload
+//	DELETE /jobs/{id}         cancel a queued or running job
+//	GET    /jobs/{id}/events  Server-Sent Events progress stream
+func RegisterJobRoutes(mux *http.ServeMux, m *jobs.Manager) {
+	m.Handle(JobTypeProcess, runProcessJob(m))
+	m.Handle(JobTypeRender, runRenderJob)
+
+	mux.HandleFunc("POST /jobs", submitJob(m))
+	mux.HandleFunc("POST /jobs/render", submitRender(m))
+	mux.HandleFunc("GET /jobs/{id}", getJob(m))
+	mux.HandleFunc("DELETE /jobs/{id}", cancelJob(m))
+	mux.HandleFunc("GET /jobs/{id}/events", jobEvents(m))
+}
+
+// runProcessJob runs the full narration pipeline for a queued request.
+// When the request names its recording, a render job is queued on success.
+func runProcessJob(m *jobs.Manager) jobs.Handler {
+	return func(ctx context.Context, job *jobs.Job) (interface{}, error) {
+		req, ok := job.Payload().(models.ProcessingRequest)
+		if !ok {
+			return nil, fmt.Errorf("unexpected payload %T", job.Payload())
+		}
+
+		resp, err := runPipeline(ctx, req)
+		if err != nil || req.VideoPath == "" {
+			return resp, err
+		}
+
+		renderJob, err := m.Submit(JobTypeRender, renderRequestFromResult(resp, req.VideoPath))
+		if err != nil {
+			// The narration itself succeeded; the render can be retried via POST /jobs/render
+			log.Printf("[WARN] Could not queue render for job %s: %v", job.ID(), err)
+			return resp, nil
+		}
+		resp["renderJobId"] = renderJob.ID()
+		return resp, nil
+	}
+}
+
+func submitJob(m *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		// Validate up front so malformed requests never occupy a worker
+		req, err := decodeRequest(bodyBytes)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		job, err := m.Submit(JobTypeProcess, *req)
+		if err != nil {
+			writeSubmitError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/jobs/"+job.ID())
+		writeJSON(w, http.StatusAccepted, job.Status())
+	}
+}
+
+// writeSubmitError maps a failed Submit to a response
+func writeSubmitError(w http.ResponseWriter, err error) {
+	if errors.Is(err, jobs.ErrQueueFull) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func getJob(m *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, ok := m.Get(r.PathValue("id"))
+		if !ok {
+			http.Error(w, jobs.ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, job.Status())
+	}
+}
+
+func cancelJob(m *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := m.Cancel(r.PathValue("id"))
+		switch {
+		case errors.Is(err, jobs.ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, jobs.ErrFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, job.Status())
+	}
+}
@@ -0,0 +1,528 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"godemo/internal/audio"
+	"godemo/internal/cache"
+	"godemo/internal/camera"
+	"godemo/internal/captions"
+	"godemo/internal/cursor"
+	"godemo/internal/duration"
+	"godemo/internal/effects"
+	"godemo/internal/instructions"
+	"godemo/internal/llm"
+	"godemo/internal/models"
+	"godemo/internal/normalize"
+	"godemo/internal/policy"
+	"godemo/internal/progress"
+	"godemo/internal/script"
+	"godemo/internal/segment"
+	"godemo/internal/timeline"
+	"godemo/internal/validate"
+	"godemo/internal/windows"
+)
+
+// pipelineError carries the HTTP status a pipeline failure maps to
+type pipelineError struct {
+	status int
+	err    error
+	body   interface{} // optional JSON body instead of the plain error text
+}
+
+func (e *pipelineError) Error() string { return e.err.Error() }
+func (e *pipelineError) Unwrap() error { return e.err }
+
+func badRequest(err error) error {
+	return &pipelineError{status: http.StatusBadRequest, err: err}
+}
+
+func internalError(err error) error {
+	return &pipelineError{status: http.StatusInternalServerError, err: err}
+}
+
+// validationError fails a strict-mode request, returning the report as the body
+func validationError(report *validate.Report) error {
+	return &pipelineError{
+		status: http.StatusUnprocessableEntity,
+		err:    fmt.Errorf("output validation failed with %d errors", report.Errors),
+		body: map[string]interface{}{
+			"error":      "output validation failed",
+			"validation": report,
+		},
+	}
+}
+
+// policyError fails a request whose script matched a block rule, returning the policy report as the body
+func policyError(report *policy.Report) error {
+	return &pipelineError{
+		status: http.StatusUnprocessableEntity,
+		err:    fmt.Errorf("narration blocked by content policy %s", report.Source),
+		body: map[string]interface{}{
+			"error":  "narration blocked by content policy",
+			"policy": report,
+		},
+	}
+}
+
+// errorStatus returns the HTTP status for an error produced by the pipeline
+func errorStatus(err error) int {
+	var pe *pipelineError
+	if errors.As(err, &pe) {
+		return pe.status
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeRequest parses either the raw (deepgramRaw/domRaw) or the structured request format
+func decodeRequest(bodyBytes []byte) (*models.ProcessingRequest, error) {
+	var rawData models.RawProcessingRequest
+	var req models.ProcessingRequest
+
+	// Attempt to parse as RAW first
+	if err := json.Unmarshal(bodyBytes, &rawData); err == nil && len(rawData.DeepgramRaw) > 0 {
+		log.Println("[INFO] Detected RAW request format, transforming...")
+		transformed, err := transformRawRequest(rawData)
+		if err != nil {
+			return nil, badRequest(fmt.Errorf("transform error: %v", err))
+		}
+		req = *transformed
+	} else {
+		// Fallback to standard structured request
+		if err := json.Unmarshal(bodyBytes, &req); err != nil {
+			return nil, badRequest(errors.New("invalid JSON structure"))
+		}
+	}
+
+	if req.VideoDurationSec <= 0 {
+		return nil, badRequest(errors.New("videoDurationSec required (checking Deepgram metadata.duration)"))
+	}
+
+	// Output files are named after the session, so the ID must give a usable name
+	if models.SessionFileName(req.SessionID) == "" {
+		return nil, badRequest(fmt.Errorf("invalid sessionId %q", req.SessionID))
+	}
+
+	return &req, nil
+}
+
+// runPipeline turns a decoded request into the response payload.
+// Stage progress is emitted on ctx; cancelling ctx stops LLM, TTS and ffmpeg work.
+func runPipeline(
+	ctx context.Context,
+	req models.ProcessingRequest,
+) (map[string]interface{}, error) {
+
+	// Count LLM and TTS cache hits so the response shows what this run cost
+	ctx, cacheStats := cache.WithStats(ctx)
+
+	// Resolve the TTS backend up front so a bad provider fails before any paid LLM call
+	synth, err := audio.NewSynthesizer(req.TTSProvider, audio.ConfigFromEnv())
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	target := audio.LoudnessTargetFromEnv()
+	if req.TargetLUFS != 0 {
+		if req.TargetLUFS < audio.MinTargetLUFS || req.TargetLUFS > audio.MaxTargetLUFS {
+			return nil, badRequest(fmt.Errorf("targetLufs must be between %.0f and %.0f", audio.MinTargetLUFS, audio.MaxTargetLUFS))
+		}
+		target.Integrated = req.TargetLUFS
+	}
+
+	// Content policy files are per tenant; a broken one fails before any paid call
+	pol, err := policy.ForTenant(req.Tenant)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	// Without a usable LLM the script falls back to deterministic narration
+	var refiner llm.ScriptRefiner
+	switch req.Mode {
+	case "", script.ModeLLM:
+		if _, err := llm.GetPreset(req.Preset); err != nil {
+			return nil, badRequest(err)
+		}
+		if refiner, err = llm.NewRefiner(llm.ConfigFromEnv()); err != nil {
+			log.Printf("[WARN] LLM unavailable, using deterministic narration: %v", err)
+			refiner = nil
+		}
+	case script.ModeDeterministic:
+	default:
+		return nil, badRequest(fmt.Errorf("unknown mode %q (want %q or %q)", req.Mode, script.ModeLLM, script.ModeDeterministic))
+	}
+
+	// 1. Normalize DOM events to timeline actions
+	progress.StageStarted(ctx, progress.StageNormalize)
+	actions, err := normalize.NormalizeDomEvents(
+		req.DomEvents,
+		req.RecordingStartTimeMs,
+		req.VideoDurationSec,
+	)
+	if err != nil {
+		progress.StageFailed(ctx, progress.StageNormalize, err)
+		return nil, badRequest(err)
+	}
+	pointer := normalize.PointerSamples(req.DomEvents, req.RecordingStartTimeMs, req.VideoDurationSec)
+
+	// Mask personal data before the timeline reaches the LLM, TTS or the response
+	actions, redactions := normalize.RedactActions(actions)
+	var spoken []normalize.Redaction
+	req.DeepgramResponse, spoken = normalize.RedactTranscript(req.DeepgramResponse)
+	redactions = append(redactions, spoken...)
+	if len(redactions) > 0 {
+		log.Printf("[INFO] Redacted %d personal data values", len(redactions))
+	}
+	progress.StageFinished(ctx, progress.StageNormalize, fmt.Sprintf("%d actions, %d redactions", len(actions), len(redactions)))
+
+	// 2. Build canonical timeline (Speech + Actions)
+	progress.StageStarted(ctx, progress.StageTimeline)
+	tl := timeline.BuildTimeline(req.DeepgramResponse, actions)
+
+	// Dead-time compression moves actions and words onto the compressed video
+	// before anything else is timed, so narrations, instructions, effects,
+	// captions and audio all come out on the new timeline
+	sourceDuration := req.VideoDurationSec
+	var edl segment.EDL
+	if req.CompressDeadTime {
+		edl = segment.Compress(tl, req.VideoDurationSec, segment.ConfigFromEnv())
+		if edl.Changed() {
+			actions = edl.RemapTimeline(actions)
+			req.DeepgramResponse = edl.RemapTranscript(req.DeepgramResponse)
+			req.VideoDurationSec = edl.Duration()
+			for i := range redactions {
+				redactions[i].T = edl.Remap(redactions[i].T)
+			}
+			for i := range pointer {
+				pointer[i].T = edl.Remap(pointer[i].T)
+			}
+			tl = timeline.BuildTimeline(req.DeepgramResponse, actions)
+			log.Printf("[SEGMENT] Compressed %.1fs to %.1fs with %d edits", sourceDuration, req.VideoDurationSec, len(edl))
+		}
+	}
+	progress.StageFinished(ctx, progress.StageTimeline, fmt.Sprintf("%d items", len(tl)))
+
+	// 3. Use LLM to refine script, or narrate deterministically without it
+	progress.StageStarted(ctx, progress.StageScript)
+	narrations, scriptMode, err := writeScript(ctx, refiner, req, actions, tl)
+	if err != nil {
+		progress.StageFailed(ctx, progress.StageScript, err)
+		return nil, err
+	}
+	progress.StageFinished(ctx, progress.StageScript, fmt.Sprintf("%d narrations (%s)", len(narrations), scriptMode))
+
+	// Policy rewrites land before synthesis so the voice says the approved text
+	var policyReport *policy.Report
+	if pol != nil {
+		progress.StageStarted(ctx, progress.StagePolicy)
+		narrations, policyReport = pol.Apply(narrations)
+		if policyReport.Blocked {
+			err := policyError(policyReport)
+			progress.StageFailed(ctx, progress.StagePolicy, err)
+			return nil, err
+		}
+		progress.StageFinished(ctx, progress.StagePolicy, fmt.Sprintf("%d warnings, %d rewrites", policyReport.Warnings, policyReport.Rewrites))
+	}
+
+	// 4. Generate Replay Instructions & Effects
+	progress.StageStarted(ctx, progress.StageEffects)
+	win := windows.ExtractNarrationWindows(tl, req.VideoDurationSec)
+	replayInst, _ := instructions.GenerateActionInstructions(actions, req.VideoDurationSec)
+	fx := effects.GenerateEffects(actions, win, req.VideoDurationSec)
+	fx = append(fx, normalize.BlurEffects(actions, redactions, req.VideoDurationSec)...)
+	fx = append(fx, cursor.ClickEffects(actions, req.VideoDurationSec)...)
+	cameraPath := camera.GeneratePath(actions, req.VideoDurationSec, camera.ConfigFromEnv())
+	cursorTrack := cursor.GenerateTrack(actions, pointer, req.VideoDurationSec)
+	progress.StageFinished(ctx, progress.StageEffects, fmt.Sprintf("%d effects, %d camera keyframes", len(fx), len(cameraPath)))
+
+	// Each narration may use the time until the next one starts, and the last
+	// one the rest of the video, which is the same slot duration fitting works with
+	var narrationWindows []models.Window
+	for i, n := range narrations {
+		end := n.End
+		if i < len(narrations)-1 && narrations[i+1].Start > end {
+			end = narrations[i+1].Start
+		}
+		if i == len(narrations)-1 && req.VideoDurationSec > end {
+			end = req.VideoDurationSec
+		}
+		narrationWindows = append(narrationWindows, models.Window{
+			Start: n.Start,
+			End:   end,
+		})
+	}
+
+	// 5. Generate Audio (synthesis and mix stages are reported by the audio package)
+	var audioFile string
+	var chunks []models.AudioChunk
+	failedChunks := []models.FailedChunk{} // chunks missing from the mix, always reported
+	if len(narrations) > 0 {
+		chunks, _ = audio.MapNarrationsToAudioChunks(narrations, narrationWindows, synth.Name())
+		var failed []models.FailedChunk
+		chunks, failed, err = synthesizeAndFit(ctx, chunks, synth, refiner, pol, policyReport, req)
+		failedChunks = append(failedChunks, failed...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("[WARN] Audio generation failed: %v", err)
+		}
+
+		// Fitting may have shortened the text; report what is actually spoken
+		for _, c := range chunks {
+			if c.NarrationIndex >= 0 && c.NarrationIndex < len(narrations) {
+				narrations[c.NarrationIndex].Text = c.Text
+			}
+		}
+	}
+
+	// 6. Optionally repair what validation would flag, before anything is mixed
+	var repairs []validate.Repair
+	if req.Repair {
+		progress.StageStarted(ctx, progress.StageRepair)
+		var repaired validate.Output
+		repaired, repairs = validate.RepairOutput(validate.Output{
+			VideoDuration: req.VideoDurationSec,
+			Windows:       narrationWindows,
+			Narrations:    narrations,
+			AudioChunks:   chunks,
+			Actions:       replayInst,
+			Effects:       fx,
+		})
+		narrationWindows, narrations, chunks = repaired.Windows, repaired.Narrations, repaired.AudioChunks
+		replayInst, fx = repaired.Actions, repaired.Effects
+		progress.StageFinished(ctx, progress.StageRepair, fmt.Sprintf("%d repairs", len(repairs)))
+	}
+
+	var loudness *models.LoudnessReport
+	if len(chunks) > 0 {
+		audioFile = models.SessionFileName(req.SessionID) + ".mp3"
+		preset := req.Preset
+		if preset == "" {
+			preset = llm.DefaultPresetName()
+		}
+		mix := audio.MixSettingsFor(preset, req.Mix)
+		if loudness, err = audio.MixAudio(ctx, chunks, audioFile, req.VideoDurationSec, mix, target); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("[WARN] Audio mix failed: %v", err)
+			audioFile = "" // no track; a file left by an earlier run must not be served
+		}
+	}
+
+	// 7. Validate the assembled output
+	progress.StageStarted(ctx, progress.StageValidate)
+	report := validate.ValidateFinalOutput(req.ValidationMode, req.VideoDurationSec, narrationWindows, narrations, chunks, replayInst, fx)
+	progress.StageFinished(ctx, progress.StageValidate, fmt.Sprintf("%d errors, %d warnings", report.Errors, report.Warnings))
+	if report.Failed() {
+		return nil, validationError(report)
+	}
+
+	// 8. Captions follow the fitted audio, or real word timings when the TTS reported them
+	cues := captions.BuildCues(narrations, chunks, captions.DefaultConfig())
+	captionFiles := map[string]string{}
+	if len(cues) > 0 {
+		if err := captions.WriteFiles(req.SessionID, cues); err != nil {
+			log.Printf("[WARN] Failed writing captions: %v", err)
+		} else {
+			name := url.PathEscape(models.SessionFileName(req.SessionID))
+			captionFiles["srt"] = "/captions/" + name + ".srt"
+			captionFiles["vtt"] = "/captions/" + name + ".vtt"
+		}
+	}
+
+	// 9. Response Construction
+	audioURL := ""
+	if audioFile != "" {
+		audioURL = "/audio/" + audioFile
+	}
+	resp := map[string]interface{}{
+		"sessionId":      req.SessionID,
+		"videoDuration":  req.VideoDurationSec,
+		"scriptMode":     scriptMode,
+		"narrations":     narrations,
+		"instructions":   replayInst,
+		"displayEffects": fx,
+		"camera":         cameraPath,
+		"cursor":         cursorTrack,
+		"audioFile":      audioURL,
+		"audioChunks":    chunks,
+		"failedChunks":   failedChunks,
+		"loudness":       loudness,
+		"validation":     report,
+		"policy":         policyReport,
+		"redactions":     redactions,
+		"captions":       captionFiles,
+		"cache":          cacheStats.Snapshot(),
+	}
+	if req.Repair {
+		resp["repairs"] = repairs
+	}
+	if req.CompressDeadTime {
+		resp["sourceDuration"] = sourceDuration
+		resp["edl"] = []models.Edit(edl)
+	}
+
+	return resp, nil
+}
+
+// synthesizeAndFit voices every chunk and fits each into its slot.
+// The returned chunks carry their measured durations and fit decisions;
+// chunks that could not be synthesized are returned separately.
+func synthesizeAndFit(
+	ctx context.Context,
+	chunks []models.AudioChunk,
+	synth audio.Synthesizer,
+	refiner llm.ScriptRefiner,
+	pol *policy.Policy,
+	policyReport *policy.Report,
+	req models.ProcessingRequest,
+) ([]models.AudioChunk, []models.FailedChunk, error) {
+
+	chunks, failed, err := audio.SynthesizeChunks(ctx, chunks, synth, req.Voice, audio.SynthesisConfigFromEnv())
+	if err != nil {
+		return nil, failed, err
+	}
+
+	// Deterministic narration has no LLM to rewrite with; fitting then only shifts, stretches and trims
+	var rewrite duration.Rewriter
+	if refiner != nil {
+		rewrite = shortenChunk(refiner, synth, pol, policyReport, req.Voice)
+	}
+
+	progress.StageStarted(ctx, progress.StageFit)
+	chunks = duration.FitChunks(ctx, chunks, req.VideoDurationSec, duration.ConfigFromEnv(), rewrite)
+	progress.StageFinished(ctx, progress.StageFit, fmt.Sprintf("%d chunks fitted", len(chunks)))
+
+	return chunks, failed, nil
+}
+
+// shortenChunk rewrites a chunk's text with the LLM and re-synthesizes it. The
+// new text goes through the policy again; a rewrite that would be blocked is
+// rejected, leaving the fitter with the approved original.
+func shortenChunk(refiner llm.ScriptRefiner, synth audio.Synthesizer, pol *policy.Policy, policyReport *policy.Report, voice string) duration.Rewriter {
+	return func(ctx context.Context, chunk models.AudioChunk, maxDuration float64) (models.AudioChunk, error) {
+		// Budget words using this voice's measured speaking rate
+		wordsPerSec := float64(len(strings.Fields(chunk.Text))) / chunk.Duration
+		maxWords := int(maxDuration * wordsPerSec * 0.95)
+
+		text, err := llm.ShortenText(ctx, refiner, chunk.Text, maxWords, maxDuration)
+		if err != nil {
+			return chunk, err
+		}
+		if pol != nil {
+			var report *policy.Report
+			text, report = pol.Recheck(chunk.WindowIndex, chunk.Text, text)
+			if report.Blocked {
+				return chunk, policyError(report)
+			}
+			policyReport.Merge(report)
+		}
+
+		result, err := audio.GenerateAudioBytes(ctx, synth, audio.SynthesisRequest{Text: text, Voice: voice})
+		if err != nil {
+			return chunk, err
+		}
+
+		chunk.Text = text
+		chunk.AudioBytes = result.Audio
+		chunk.AudioFormat = result.Format
+		chunk.Duration = result.Duration
+		chunk.Words = result.Words
+		return chunk, nil
+	}
+}
+
+// writeScript produces the narrations and reports which mode wrote them. A nil
+// refiner or a failed LLM call falls back to deterministic narration, which has
+// the same shape.
+func writeScript(
+	ctx context.Context,
+	refiner llm.ScriptRefiner,
+	req models.ProcessingRequest,
+	actions []models.TimelineItem,
+	tl []models.TimelineItem,
+) ([]models.Narration, string, error) {
+
+	if refiner != nil {
+		narrations, err := refineNarrations(ctx, refiner, req, actions)
+		if err == nil {
+			return narrations, script.ModeLLM, nil
+		}
+		if ctx.Err() != nil {
+			return nil, "", ctx.Err()
+		}
+		log.Printf("[WARN] LLM script failed, falling back to deterministic narration: %v", err)
+	}
+
+	narrations, err := script.GenerateNarrations(windows.ExtractNarrationWindows(tl, req.VideoDurationSec), tl)
+	if err != nil {
+		return nil, "", internalError(err)
+	}
+	return narrations, script.ModeDeterministic, nil
+}
+
+// refineNarrations asks the configured LLM provider for a polished narration script
+func refineNarrations(ctx context.Context, refiner llm.ScriptRefiner, req models.ProcessingRequest, actions []models.TimelineItem) ([]models.Narration, error) {
+	var narrations []models.Narration
+
+	if req.DeepgramResponse != nil {
+		// Extract full transcript
+		var transcriptWords []string
+		for _, word := range req.DeepgramResponse.Words {
+			if word.PunctuatedWord != "" {
+				transcriptWords = append(transcriptWords, word.PunctuatedWord)
+			} else {
+				transcriptWords = append(transcriptWords, word.Word)
+			}
+		}
+		fullTranscript := strings.Join(transcriptWords, " ")
+
+		// Call LLM for refinement
+		refinedSegments, err := llm.RefineScript(ctx, refiner, llm.RefineScriptRequest{
+			RawTranscript: fullTranscript,
+			Actions:       actions,
+			VideoDuration: req.VideoDurationSec,
+			DeepgramWords: req.DeepgramResponse.Words,
+			Preset:        req.Preset,
+			Vars: llm.PromptVars{
+				Topic:    req.Topic,
+				Audience: req.Audience,
+				Product:  req.Product,
+				Language: req.Language,
+			},
+		})
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, internalError(fmt.Errorf("LLM error: %v", err))
+		}
+
+		if len(refinedSegments) == 0 {
+			return nil, internalError(errors.New("LLM returned no narration segments"))
+		}
+
+		// Convert LLM segments to Narration format
+		for i, seg := range refinedSegments {
+			narrations = append(narrations, models.Narration{
+				WindowIndex: i,
+				Start:       seg.Start,
+				End:         seg.End,
+				Text:        seg.Text,
+				MusicStyle:  seg.MusicStyle,
+			})
+		}
+	}
+
+	return narrations, nil
+}
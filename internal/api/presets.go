@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"godemo/internal/llm"
+)
+
+// ListPresets returns every narration prompt preset
+func ListPresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"default": llm.DefaultPresetName(),
+		"presets": llm.ListPresets(),
+	})
+}
+
+// GetPreset returns one preset with its template
+func GetPreset(w http.ResponseWriter, r *http.Request) {
+	p, err := llm.GetPreset(r.PathValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// PutPreset creates or replaces a runtime preset. Built-in names may be overridden.
+func PutPreset(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Description string `json:"description"`
+		Template    string `json:"template"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON structure", http.StatusBadRequest)
+		return
+	}
+
+	p, err := llm.SavePreset(r.PathValue("name"), body.Description, body.Template)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// DeletePreset removes a runtime preset, restoring the built-in it overrode
+func DeletePreset(w http.ResponseWriter, r *http.Request) {
+	err := llm.DeletePreset(r.PathValue("name"))
+	switch {
+	case errors.Is(err, llm.ErrPresetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, llm.ErrPresetBuiltin):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"godemo/internal/jobs"
+	"godemo/internal/models"
+	"godemo/internal/render"
+)
+
+// renderJobRequest is the body of POST /jobs/render. Audio, effects and duration
+// are taken from processJobId when given; fields sent inline take precedence.
+type renderJobRequest struct {
+	render.Request
+	ProcessJobID string `json:"processJobId,omitempty"`
+}
+
+// runRenderJob renders the MP4 for a finished narration
+func runRenderJob(ctx context.Context, job *jobs.Job) (interface{}, error) {
+	req, ok := job.Payload().(render.Request)
+	if !ok {
+		return nil, fmt.Errorf("unexpected payload %T", job.Payload())
+	}
+
+	outPath, err := render.Render(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"sessionId": req.SessionID,
+		"videoFile": "/renders/" + filepath.Base(outPath),
+	}, nil
+}
+
+func submitRender(m *jobs.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body renderJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON structure", http.StatusBadRequest)
+			return
+		}
+
+		req, err := resolveRenderRequest(m, body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		job, err := m.Submit(JobTypeRender, req)
+		if err != nil {
+			writeSubmitError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", "/jobs/"+job.ID())
+		writeJSON(w, http.StatusAccepted, job.Status())
+	}
+}
+
+// resolveRenderRequest fills a render request from the narration job it refers to
+func resolveRenderRequest(m *jobs.Manager, body renderJobRequest) (render.Request, error) {
+	req := body.Request
+
+	if body.ProcessJobID != "" {
+		job, ok := m.Get(body.ProcessJobID)
+		if !ok || job.Type() != JobTypeProcess {
+			return req, &pipelineError{status: http.StatusNotFound, err: jobs.ErrNotFound}
+		}
+
+		status := job.Status()
+		if status.State != jobs.StateDone {
+			return req, &pipelineError{
+				status: http.StatusConflict,
+				err:    fmt.Errorf("job %s is %s, not done", job.ID(), status.State),
+			}
+		}
+
+		resp, _ := status.Result.(map[string]interface{})
+		videoPath := req.VideoPath
+		if videoPath == "" {
+			if p, ok := job.Payload().(models.ProcessingRequest); ok {
+				videoPath = p.VideoPath
+			}
+		}
+		fromJob := renderRequestFromResult(resp, videoPath)
+
+		if req.SessionID == "" {
+			req.SessionID = fromJob.SessionID
+		}
+		if req.VideoPath == "" {
+			req.VideoPath = fromJob.VideoPath
+		}
+		if req.AudioFile == "" {
+			req.AudioFile = fromJob.AudioFile
+		}
+		if req.VideoDuration == 0 {
+			req.VideoDuration = fromJob.VideoDuration
+		}
+		if req.DisplayEffects == nil {
+			req.DisplayEffects = fromJob.DisplayEffects
+		}
+		if req.Edits == nil {
+			req.Edits = fromJob.Edits
+		}
+	}
+
+	if req.SessionID == "" {
+		return req, badRequest(errors.New("sessionId or processJobId required"))
+	}
+	if models.SessionFileName(req.SessionID) == "" {
+		return req, badRequest(fmt.Errorf("invalid sessionId %q", req.SessionID))
+	}
+	if _, err := render.ResolveVideo(req.VideoPath); err != nil {
+		return req, badRequest(err)
+	}
+	return req, nil
+}
+
+// renderRequestFromResult builds a render request from a pipeline response
+func renderRequestFromResult(resp map[string]interface{}, videoPath string) render.Request {
+	req := render.Request{VideoPath: videoPath}
+	req.SessionID, _ = resp["sessionId"].(string)
+	req.VideoDuration, _ = resp["videoDuration"].(float64)
+	req.DisplayEffects, _ = resp["displayEffects"].([]models.DisplayEffect)
+	req.Edits, _ = resp["edl"].([]models.Edit)
+	req.AudioFile, _ = resp["audioFile"].(string) // empty when there is no narration track
+	return req
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"godemo/internal/models"
+	"godemo/internal/rrweb"
 )
 
 // transformRawRequest converts the raw JSON outputs into our internal ProcessingRequest structure
@@ -25,29 +26,86 @@ func transformRawRequest(raw models.RawProcessingRequest) (*models.ProcessingReq
 		return nil, fmt.Errorf("failed to parse deepgramRaw: %v", err)
 	}
 
-	// 2. Parse DOM Raw
+	// 2. Parse DOM Raw, importing rrweb recordings into the same event shape
+	var sessionID string
+	var startTime int64
+	var transformedEvents []models.DomEvent
+	if rrweb.Detect(raw.DomRaw) {
+		rec, err := rrweb.Import(raw.DomRaw)
+		if err != nil {
+			return nil, err
+		}
+		sessionID, startTime, transformedEvents = rec.SessionID, rec.StartTime, rec.Events
+	} else {
+		var err error
+		sessionID, startTime, transformedEvents, err = parseDomRaw(raw.DomRaw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.ProcessingRequest{
+		ProcessingOptions:    raw.ProcessingOptions,
+		SessionID:            sessionID,
+		RecordingStartTimeMs: startTime,
+		VideoDurationSec:     dg.Metadata.Duration,
+		DeepgramResponse: &models.DeepgramResult{
+			Words: dg.Results.Channels[0].Alternatives[0].Words,
+		},
+		DomEvents: transformedEvents,
+	}, nil
+}
+
+// parseDomRaw reads the capture extension's {sessionId, startTime, events} format
+func parseDomRaw(domRaw json.RawMessage) (string, int64, []models.DomEvent, error) {
 	var dr struct {
-		SessionID string `json:"sessionId"`
-		StartTime int64  `json:"startTime"`
+		SessionID string           `json:"sessionId"`
+		StartTime int64            `json:"startTime"`
+		URL       string           `json:"url"`
+		Viewport  *models.Viewport `json:"viewport"`
 		Events    []struct {
 			Type      string                 `json:"type"`
 			Timestamp int64                  `json:"timestamp"`
 			Target    map[string]interface{} `json:"target"`
+			Metadata  *models.EventMetadata  `json:"metadata"`
+			X         *float64               `json:"x"` // pointer position of mousemove samples
+			Y         *float64               `json:"y"`
 		} `json:"events"`
 	}
-	if err := json.Unmarshal(raw.DomRaw, &dr); err != nil {
-		return nil, fmt.Errorf("failed to parse domRaw: %v", err)
+	if err := json.Unmarshal(domRaw, &dr); err != nil {
+		return "", 0, nil, fmt.Errorf("failed to parse domRaw: %v", err)
 	}
 
-	// 3. Transform Events
+	// Transform Events
 	var transformedEvents []models.DomEvent
 	for _, e := range dr.Events {
+		// Pointer samples feed the cursor track only
+		if e.Type == "mousemove" && e.X != nil && e.Y != nil {
+			evt := models.DomEvent{
+				Type:      e.Type,
+				Timestamp: dr.StartTime + e.Timestamp,
+				Pointer:   &models.Point{X: *e.X, Y: *e.Y},
+				Metadata:  e.Metadata,
+			}
+			if evt.Metadata == nil && dr.Viewport != nil {
+				evt.Metadata = &models.EventMetadata{Viewport: dr.Viewport}
+			}
+			transformedEvents = append(transformedEvents, evt)
+			continue
+		}
+
 		// Only process relevant interaction events
 		if e.Type == "click" || e.Type == "scroll" || e.Type == "input" || e.Type == "navigation" {
 			evt := models.DomEvent{
 				Type:      e.Type,
 				Timestamp: dr.StartTime + e.Timestamp, // Make timestamp absolute
 				Target:    e.Target,
+				Metadata:  e.Metadata,
+			}
+
+			// Events recorded without metadata inherit the recording's page
+			if evt.Metadata == nil && (dr.URL != "" || dr.Viewport != nil) {
+				evt.Metadata = &models.EventMetadata{URL: dr.URL, Viewport: dr.Viewport}
 			}
 
 			// Extract bounds if available in target.bbox
@@ -71,15 +129,7 @@ func transformRawRequest(raw models.RawProcessingRequest) (*models.ProcessingReq
 		}
 	}
 
-	return &models.ProcessingRequest{
-		SessionID:            dr.SessionID,
-		RecordingStartTimeMs: dr.StartTime,
-		VideoDurationSec:     dg.Metadata.Duration,
-		DeepgramResponse: &models.DeepgramResult{
-			Words: dg.Results.Channels[0].Alternatives[0].Words,
-		},
-		DomEvents: transformedEvents,
-	}, nil
+	return dr.SessionID, dr.StartTime, transformedEvents, nil
 }
 
 func getFloat(v interface{}) float64 {
@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"log"
+
+	"godemo/internal/cache"
+)
+
+// configured is implemented by synthesizers built from a ProviderConfig
+type configured interface {
+	config() ProviderConfig
+}
+
+// synthesisCacheKey identifies a synthesis by text, voice, provider and format.
+// The provider's default model and voice are included because an empty
+// req.Voice resolves to them. Synthesizers without a config are not cached.
+func synthesisCacheKey(synth Synthesizer, req SynthesisRequest) string {
+	c, ok := synth.(configured)
+	if !ok {
+		return ""
+	}
+	cfg := c.config()
+	return cache.Key(cache.NamespaceTTS,
+		synth.Name(),
+		cfg.BaseURL,
+		cfg.Model,
+		cfg.Voice,
+		req.Voice,
+		req.Format,
+		req.Text,
+	)
+}
+
+// cachedSynthesis returns the result stored for key, recording the lookup in ctx
+func cachedSynthesis(ctx context.Context, key string) (*SynthesisResult, bool) {
+	if key == "" {
+		return nil, false
+	}
+	if data, ok := cache.Default().Get(key); ok {
+		var result SynthesisResult
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&result); err == nil {
+			cache.Record(ctx, cache.NamespaceTTS, true)
+			return &result, true
+		}
+		log.Printf("[WARN] Discarding unreadable TTS cache entry %s", key)
+	}
+	cache.Record(ctx, cache.NamespaceTTS, false)
+	return nil, false
+}
+
+func storeSynthesis(key string, result *SynthesisResult) {
+	if key == "" {
+		return
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(result); err == nil {
+		cache.Default().Put(key, buf.Bytes())
+	}
+}
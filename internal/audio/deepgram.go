@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// DeepgramSynthesizer calls the Deepgram Aura speak API. Voice is the Aura model name.
+type DeepgramSynthesizer struct {
+	cfg ProviderConfig
+}
+
+func (d *DeepgramSynthesizer) Name() string           { return TTSDeepgram }
+func (d *DeepgramSynthesizer) config() ProviderConfig { return d.cfg }
+
+func (d *DeepgramSynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error) {
+	if d.cfg.APIKey == "" {
+		return nil, errors.New("DEEPGRAM_API_KEY is not configured")
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = d.cfg.Voice
+	}
+
+	// Improve punctuation for more natural pauses
+	// Adding a period after an exclamation mark usually forces a better pause in Deepgram
+	text := strings.ReplaceAll(req.Text, "!", "!. ")
+	text = strings.ReplaceAll(text, "?", "?. ")
+
+	q := url.Values{"model": {voice}}
+	format := FormatMP3
+	if req.Format == FormatWAV {
+		format = FormatWAV
+		q.Set("encoding", "linear16")
+		q.Set("container", "wav")
+	}
+
+	audioBytes, err := postAudio(ctx, d.cfg.BaseURL+"?"+q.Encode(), map[string]string{
+		"Authorization": "Token " + d.cfg.APIKey,
+	}, map[string]string{"text": text})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SynthesisResult{Audio: audioBytes, Format: format}, nil
+}
@@ -0,0 +1,108 @@
+package audio
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"godemo/internal/models"
+)
+
+// ElevenLabsSynthesizer calls the ElevenLabs text-to-speech API. Voice is a voice ID.
+// It uses the with-timestamps endpoint so chunks come back with word timings.
+type ElevenLabsSynthesizer struct {
+	cfg ProviderConfig
+}
+
+func (e *ElevenLabsSynthesizer) Name() string           { return TTSElevenLab }
+func (e *ElevenLabsSynthesizer) config() ProviderConfig { return e.cfg }
+
+func (e *ElevenLabsSynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error) {
+	if e.cfg.APIKey == "" {
+		return nil, errors.New("ELEVENLABS_API_KEY is not configured")
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = e.cfg.Voice
+	}
+
+	outputFormat, format := "mp3_44100_128", FormatMP3
+	if req.Format == FormatWAV {
+		outputFormat, format = "pcm_44100", FormatWAV
+	}
+
+	// The voice ID is a path segment; escaping keeps it from changing the endpoint
+	if voice == "." || voice == ".." {
+		return nil, fmt.Errorf("invalid ElevenLabs voice %q", voice)
+	}
+	endpoint := fmt.Sprintf("%s/text-to-speech/%s/with-timestamps?output_format=%s", strings.TrimSuffix(e.cfg.BaseURL, "/"), url.PathEscape(voice), outputFormat)
+	body, err := postAudio(ctx, endpoint, map[string]string{
+		"xi-api-key": e.cfg.APIKey,
+	}, map[string]interface{}{
+		"text":     req.Text,
+		"model_id": e.cfg.Model,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		AudioBase64 string `json:"audio_base64"`
+		Alignment   struct {
+			Characters []string  `json:"characters"`
+			Starts     []float64 `json:"character_start_times_seconds"`
+			Ends       []float64 `json:"character_end_times_seconds"`
+		} `json:"alignment"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse ElevenLabs response: %v", err)
+	}
+
+	audioBytes, err := base64.StdEncoding.DecodeString(resp.AudioBase64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ElevenLabs audio: %v", err)
+	}
+
+	if format == FormatWAV {
+		// ElevenLabs returns headerless PCM; wrap it so ffmpeg can read it
+		audioBytes = wrapPCM16(audioBytes, 44100, 1)
+	}
+
+	a := resp.Alignment
+	return &SynthesisResult{
+		Audio:  audioBytes,
+		Format: format,
+		Words:  wordsFromCharacters(a.Characters, a.Starts, a.Ends),
+	}, nil
+}
+
+// wordsFromCharacters folds character-level alignment into whitespace-separated words
+func wordsFromCharacters(chars []string, starts, ends []float64) []models.WordTiming {
+	if len(starts) < len(chars) || len(ends) < len(chars) {
+		return nil
+	}
+
+	var words []models.WordTiming
+	var current *models.WordTiming
+
+	for i, c := range chars {
+		if strings.TrimFunc(c, unicode.IsSpace) == "" {
+			current = nil
+			continue
+		}
+		if current == nil {
+			words = append(words, models.WordTiming{Start: starts[i]})
+			current = &words[len(words)-1]
+		}
+		current.Word += c
+		current.End = ends[i]
+	}
+
+	return words
+}
@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// PiperSynthesizer runs the offline Piper engine. A request voice is the bare name
+// of a .onnx model in the voices directory; without one PIPER_MODEL is used.
+type PiperSynthesizer struct {
+	cfg ProviderConfig
+}
+
+func (p *PiperSynthesizer) Name() string           { return TTSPiper }
+func (p *PiperSynthesizer) config() ProviderConfig { return p.cfg }
+
+func (p *PiperSynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error) {
+	model, err := p.resolveModel(req.Voice)
+	if err != nil {
+		return nil, err
+	}
+
+	return runLocalEngine(ctx, req.Text, func(outPath string) *exec.Cmd {
+		return exec.CommandContext(ctx, p.cfg.Binary, "--model", model, "--output_file", outPath)
+	})
+}
+
+// resolveModel maps a request voice to a model file under the voices directory.
+// Voices are names, never paths, so a request cannot load an arbitrary file.
+func (p *PiperSynthesizer) resolveModel(voice string) (string, error) {
+	if voice != "" && p.cfg.Voices == "" {
+		log.Printf("[WARN] Piper voice %q ignored: PIPER_VOICES_DIR is not configured", voice)
+		voice = ""
+	}
+	if voice == "" {
+		if p.cfg.Model == "" {
+			return "", errors.New("PIPER_MODEL is not configured")
+		}
+		return p.cfg.Model, nil
+	}
+
+	if voice == "." || voice == ".." || strings.ContainsAny(voice, `/\`) {
+		return "", fmt.Errorf("invalid Piper voice %q", voice)
+	}
+	if !strings.HasSuffix(voice, ".onnx") {
+		voice += ".onnx"
+	}
+	model := filepath.Join(p.cfg.Voices, voice)
+	if _, err := os.Stat(model); err != nil {
+		return "", fmt.Errorf("unknown Piper voice %q", strings.TrimSuffix(voice, ".onnx"))
+	}
+	return model, nil
+}
+
+// EspeakSynthesizer runs espeak-ng. Lower quality than Piper but needs no voice model.
+type EspeakSynthesizer struct {
+	cfg ProviderConfig
+}
+
+func (e *EspeakSynthesizer) Name() string           { return TTSEspeak }
+func (e *EspeakSynthesizer) config() ProviderConfig { return e.cfg }
+
+func (e *EspeakSynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error) {
+	voice := req.Voice
+	if voice == "" {
+		voice = e.cfg.Voice
+	}
+
+	return runLocalEngine(ctx, req.Text, func(outPath string) *exec.Cmd {
+		return exec.CommandContext(ctx, e.cfg.Binary, "-v", voice, "-w", outPath, "--stdin")
+	})
+}
+
+// runLocalEngine feeds text on stdin to a command that writes a wav file
+func runLocalEngine(ctx context.Context, text string, build func(outPath string) *exec.Cmd) (*SynthesisResult, error) {
+	out, err := os.CreateTemp("", "tts_*.wav")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	cmd := build(out.Name())
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%s failed: %v: %s", cmd.Path, err, stderr.String())
+	}
+
+	audioBytes, err := os.ReadFile(out.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &SynthesisResult{Audio: audioBytes, Format: FormatWAV}, nil
+}
@@ -0,0 +1,137 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+const (
+	DefaultTargetLUFS = -16.0 // web; YouTube normalizes to -14
+	DefaultTruePeak   = -1.5
+	DefaultLRA        = 11.0
+	MinTargetLUFS     = -70.0
+	MaxTargetLUFS     = -5.0
+)
+
+// LoudnessTarget is the EBU R128 target applied to every chunk and to the final mix
+type LoudnessTarget struct {
+	Integrated float64 // LUFS
+	TruePeak   float64 // dBTP
+	LRA        float64 // loudness range, LU
+}
+
+// LoudnessTargetFromEnv reads LOUDNESS_TARGET_LUFS, LOUDNESS_TRUE_PEAK and LOUDNESS_LRA
+func LoudnessTargetFromEnv() LoudnessTarget {
+	t := LoudnessTarget{Integrated: DefaultTargetLUFS, TruePeak: DefaultTruePeak, LRA: DefaultLRA}
+	if v, err := strconv.ParseFloat(os.Getenv("LOUDNESS_TARGET_LUFS"), 64); err == nil && v >= MinTargetLUFS && v <= MaxTargetLUFS {
+		t.Integrated = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LOUDNESS_TRUE_PEAK"), 64); err == nil && v >= -9 && v <= 0 {
+		t.TruePeak = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LOUDNESS_LRA"), 64); err == nil && v >= 1 && v <= 50 {
+		t.LRA = v
+	}
+	return t
+}
+
+// loudnormReport is the JSON loudnorm prints with print_format=json. Numbers arrive as strings.
+type loudnormReport struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	OutputI      string `json:"output_i"`
+	OutputTP     string `json:"output_tp"`
+	OutputLRA    string `json:"output_lra"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// stats converts the report, leaving unmeasurable values (-inf for silence) at zero
+func (r *loudnormReport) stats() *models.LoudnessStats {
+	f := func(s string) float64 {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || v != v || v > 1e9 || v < -1e9 {
+			return 0
+		}
+		return v
+	}
+	return &models.LoudnessStats{
+		InputI:       f(r.InputI),
+		InputTP:      f(r.InputTP),
+		InputLRA:     f(r.InputLRA),
+		InputThresh:  f(r.InputThresh),
+		OutputI:      f(r.OutputI),
+		OutputTP:     f(r.OutputTP),
+		OutputLRA:    f(r.OutputLRA),
+		TargetOffset: f(r.TargetOffset),
+	}
+}
+
+// measurable reports whether the first pass produced usable values; silence measures -inf
+func (r *loudnormReport) measurable() bool {
+	_, err1 := strconv.ParseFloat(r.InputI, 64)
+	_, err2 := strconv.ParseFloat(r.InputThresh, 64)
+	return err1 == nil && err2 == nil && !strings.Contains(r.InputI, "inf")
+}
+
+// measureFilter is the first loudnorm pass, which only analyses
+func measureFilter(t LoudnessTarget) string {
+	return fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f:print_format=json", t.Integrated, t.TruePeak, t.LRA)
+}
+
+// normalizeFilter is the second pass: a linear gain computed from the first
+// pass measurements, falling back to single-pass dynamic mode without them
+func normalizeFilter(t LoudnessTarget, m *loudnormReport, printStats bool) string {
+	f := fmt.Sprintf("loudnorm=I=%.1f:TP=%.1f:LRA=%.1f", t.Integrated, t.TruePeak, t.LRA)
+	if m != nil && m.measurable() {
+		f += fmt.Sprintf(":measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true",
+			m.InputI, m.InputTP, m.InputLRA, m.InputThresh, m.TargetOffset)
+	}
+	if printStats {
+		f += ":print_format=json"
+	}
+	return f
+}
+
+// measureFile runs the first loudnorm pass over an audio file
+func measureFile(ctx context.Context, path string, t LoudnessTarget) (*loudnormReport, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-nostats", "-i", path, "-af", measureFilter(t), "-f", "null", "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("loudness measurement failed: %v: %s", err, lastLines(stderr.String(), 3))
+	}
+	return parseLoudnorm(stderr.String())
+}
+
+// parseLoudnorm extracts the last JSON block loudnorm printed to ffmpeg's stderr
+func parseLoudnorm(stderr string) (*loudnormReport, error) {
+	end := strings.LastIndex(stderr, "}")
+	start := strings.LastIndex(stderr[:max(end, 0)], "{")
+	if start < 0 || end < start {
+		return nil, errors.New("no loudnorm report in ffmpeg output")
+	}
+	var r loudnormReport
+	if err := json.Unmarshal([]byte(stderr[start:end+1]), &r); err != nil {
+		return nil, fmt.Errorf("invalid loudnorm report: %v", err)
+	}
+	return &r, nil
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+const (
+	DefaultMusicStyle = "upbeat"
+	MixSampleRate     = 44100
+)
+
+// DefaultMix is used for presets without their own mix profile
+var DefaultMix = models.MixSettings{
+	VoiceVolume:   1.5,
+	MusicBed:      0.25,
+	DuckThreshold: 0.03,
+	DuckRatio:     8,
+	AttackMs:      20,
+	ReleaseMs:     500,
+	FadeSec:       1.5,
+}
+
+// MixPresets tune the music bed to each built-in narration preset: energetic
+// presets keep the music forward, explanatory ones keep it out of the way
+var MixPresets = map[string]models.MixSettings{
+	"ecommerce-hype":            {VoiceVolume: 1.5, MusicBed: 0.35, DuckThreshold: 0.03, DuckRatio: 6, AttackMs: 20, ReleaseMs: 300, FadeSec: 1},
+	"product-marketing":         {VoiceVolume: 1.5, MusicBed: 0.3, DuckThreshold: 0.03, DuckRatio: 6, AttackMs: 20, ReleaseMs: 400, FadeSec: 1.5},
+	"neutral-tutorial":          {VoiceVolume: 1.4, MusicBed: 0.15, DuckThreshold: 0.02, DuckRatio: 10, AttackMs: 20, ReleaseMs: 600, FadeSec: 2},
+	"support-walkthrough":       {VoiceVolume: 1.4, MusicBed: 0.12, DuckThreshold: 0.02, DuckRatio: 10, AttackMs: 20, ReleaseMs: 600, FadeSec: 2},
+	"accessibility-description": {VoiceVolume: 1.4, MusicBed: 0.08, DuckThreshold: 0.01, DuckRatio: 20, AttackMs: 10, ReleaseMs: 800, FadeSec: 2},
+}
+
+// MixSettingsFor returns the preset's mix profile with any non-zero fields of override applied
+func MixSettingsFor(preset string, override *models.MixSettings) models.MixSettings {
+	mix, ok := MixPresets[preset]
+	if !ok {
+		mix = DefaultMix
+	}
+	if o := override; o != nil {
+		setIfPositive(&mix.VoiceVolume, o.VoiceVolume)
+		setIfPositive(&mix.MusicBed, o.MusicBed)
+		setIfPositive(&mix.DuckThreshold, o.DuckThreshold)
+		setIfPositive(&mix.DuckRatio, o.DuckRatio)
+		setIfPositive(&mix.AttackMs, o.AttackMs)
+		setIfPositive(&mix.ReleaseMs, o.ReleaseMs)
+		setIfPositive(&mix.FadeSec, o.FadeSec)
+	}
+
+	// Keep values inside what sidechaincompress accepts
+	mix.DuckThreshold = clamp(mix.DuckThreshold, 0.001, 1)
+	mix.DuckRatio = clamp(mix.DuckRatio, 1, 20)
+	mix.AttackMs = clamp(mix.AttackMs, 0.01, 2000)
+	mix.ReleaseMs = clamp(mix.ReleaseMs, 0.01, 9000)
+	return mix
+}
+
+// musicRun is a stretch of one music style, spanning consecutive chunks
+type musicRun struct {
+	style      string
+	input      int // ffmpeg input index of the style's track
+	start, end float64
+}
+
+// musicRuns groups consecutive chunks with the same style so the track plays
+// through instead of restarting at every line. The first run starts at 0 and the
+// last ends with the video, so music also plays before the first and after the last line.
+func musicRuns(chunks []models.AudioChunk, musicInputs map[string]int, totalDuration float64) []musicRun {
+	var runs []musicRun
+	for i, chunk := range chunks {
+		style := chunk.MusicStyle
+		if style == "" {
+			style = DefaultMusicStyle
+		}
+		end := chunk.End
+		if i < len(chunks)-1 && chunks[i+1].Start > chunk.Start {
+			end = chunks[i+1].Start
+		}
+
+		if n := len(runs); n > 0 && runs[n-1].style == style {
+			runs[n-1].end = math.Max(runs[n-1].end, end)
+			continue
+		}
+		if n := len(runs); n > 0 {
+			runs[n-1].end = chunk.Start
+		}
+		runs = append(runs, musicRun{style: style, start: chunk.Start, end: end})
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	runs[0].start = 0
+	runs[len(runs)-1].end = totalDuration
+
+	// Styles without a track leave silence
+	var out []musicRun
+	for _, r := range runs {
+		if idx, ok := musicInputs[r.style]; ok && r.end-r.start > 0.1 {
+			r.input = idx
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// musicFilters renders each run as a faded, delayed slice of its track. Runs
+// overlap by fadeSec around style changes so one track fades out as the next fades in.
+func musicFilters(runs []musicRun, mix models.MixSettings, totalDuration float64) (parts []string, labels []string) {
+	half := mix.FadeSec / 2
+	for i, r := range runs {
+		start := math.Max(r.start-half, 0)
+		end := math.Min(r.end+half, totalDuration)
+		d := end - start
+		fade := math.Min(mix.FadeSec, d/2)
+
+		chain := fmt.Sprintf("[%d:a]atrim=duration=%f,aresample=%d", r.input, d, MixSampleRate)
+		if fade > 0 {
+			chain += fmt.Sprintf(",afade=t=in:st=0:d=%f,afade=t=out:st=%f:d=%f", fade, d-fade, fade)
+		}
+		delayMs := int(start * 1000)
+		label := fmt.Sprintf("bg%d", i+1)
+		parts = append(parts, fmt.Sprintf("%s,volume=%f,adelay=%d|%d[%s]", chain, mix.MusicBed, delayMs, delayMs, label))
+		labels = append(labels, "["+label+"]")
+	}
+	return parts, labels
+}
+
+// duckFilters mixes the voice lines into one track and, when there is music,
+// ducks the music under it with a sidechain compressor keyed on the voice.
+// The graph ends in the [out] label.
+func duckFilters(voiceLabels, musicLabels []string, mix models.MixSettings) []string {
+	var parts []string
+
+	// [0] is the silent base that fixes the mix length
+	parts = append(parts, fmt.Sprintf("[0]%samix=inputs=%d:duration=longest:dropout_transition=0:normalize=0,aformat=sample_fmts=fltp:channel_layouts=stereo[voice]",
+		strings.Join(voiceLabels, ""), len(voiceLabels)+1))
+
+	if len(musicLabels) == 0 {
+		return append(parts, "[voice]anull[out]")
+	}
+
+	parts = append(parts,
+		"[voice]asplit=2[voicemix][voicekey]",
+		fmt.Sprintf("%samix=inputs=%d:duration=longest:dropout_transition=0:normalize=0,aformat=sample_fmts=fltp:channel_layouts=stereo[music]",
+			strings.Join(musicLabels, ""), len(musicLabels)),
+		fmt.Sprintf("[music][voicekey]sidechaincompress=threshold=%f:ratio=%f:attack=%f:release=%f[ducked]",
+			mix.DuckThreshold, mix.DuckRatio, mix.AttackMs, mix.ReleaseMs),
+		"[voicemix][ducked]amix=inputs=2:duration=first:dropout_transition=0:normalize=0[out]",
+	)
+	return parts
+}
+
+func setIfPositive(dst *float64, v float64) {
+	if v > 0 {
+		*dst = v
+	}
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
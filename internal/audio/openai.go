@@ -0,0 +1,47 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// OpenAISynthesizer calls an OpenAI-compatible /audio/speech endpoint
+type OpenAISynthesizer struct {
+	cfg ProviderConfig
+}
+
+func (o *OpenAISynthesizer) Name() string           { return TTSOpenAI }
+func (o *OpenAISynthesizer) config() ProviderConfig { return o.cfg }
+
+func (o *OpenAISynthesizer) Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error) {
+	// Self-hosted compatible servers often run without a key
+	headers := map[string]string{}
+	if o.cfg.APIKey != "" {
+		headers["Authorization"] = "Bearer " + o.cfg.APIKey
+	} else if strings.Contains(o.cfg.BaseURL, "api.openai.com") {
+		return nil, errors.New("OPENAI_API_KEY is not configured")
+	}
+
+	voice := req.Voice
+	if voice == "" {
+		voice = o.cfg.Voice
+	}
+
+	format := FormatMP3
+	if req.Format == FormatWAV {
+		format = FormatWAV
+	}
+
+	audioBytes, err := postAudio(ctx, strings.TrimSuffix(o.cfg.BaseURL, "/")+"/audio/speech", headers, map[string]string{
+		"model":           o.cfg.Model,
+		"input":           req.Text,
+		"voice":           voice,
+		"response_format": format,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SynthesisResult{Audio: audioBytes, Format: format}, nil
+}
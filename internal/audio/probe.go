@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProbeDuration measures the length of encoded audio in seconds using ffprobe
+func ProbeDuration(ctx context.Context, audio []byte) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		"-i", "pipe:0",
+	)
+	cmd.Stdin = bytes.NewReader(audio)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe error: %v: %s", err, stderr.String())
+	}
+
+	d, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe returned no duration: %q", out)
+	}
+	return d, nil
+}
+
+// postAudio sends a JSON payload to a TTS endpoint and returns the audio body
+func postAudio(ctx context.Context, url string, headers map[string]string, payload interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(body),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// StatusError is a non-200 reply from a TTS endpoint
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration // server-requested wait, zero when not given
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("TTS error: %s - %s", e.Status, e.Body)
+}
+
+// Temporary reports whether the request may succeed if retried (rate limits and server errors)
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(v string) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// wrapPCM16 prefixes raw 16-bit little-endian PCM with a WAV header
+func wrapPCM16(pcm []byte, sampleRate, channels int) []byte {
+	var buf bytes.Buffer
+	byteRate := sampleRate * channels * 2
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(byteRate))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
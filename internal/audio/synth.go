@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+// Supported audio formats
+const (
+	FormatMP3 = "mp3"
+	FormatWAV = "wav"
+)
+
+var ErrUnsupportedProvider = errors.New("unsupported TTS provider")
+
+// SynthesisRequest is a single piece of text to speak
+type SynthesisRequest struct {
+	Text   string
+	Voice  string // provider-specific voice/model; empty uses the configured default
+	Format string // FormatMP3 (default) or FormatWAV
+}
+
+// SynthesisResult is the synthesized audio and its measured length
+type SynthesisResult struct {
+	Audio    []byte
+	Format   string              // actual container of Audio; local engines always return wav
+	Duration float64             // seconds, measured with ffprobe
+	Words    []models.WordTiming // word-level timing when the provider reports it
+}
+
+// Synthesizer is a text-to-speech backend
+type Synthesizer interface {
+	Name() string
+	Synthesize(ctx context.Context, req SynthesisRequest) (*SynthesisResult, error)
+}
+
+// ProviderConfig holds credentials and defaults for one TTS backend
+type ProviderConfig struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	Voice   string
+	Binary  string // local engines only
+	Voices  string // local engines only: directory request voices are looked up in
+}
+
+// TTSConfig holds the configuration of every TTS backend
+type TTSConfig struct {
+	DefaultProvider string
+	Deepgram        ProviderConfig
+	ElevenLabs      ProviderConfig
+	OpenAI          ProviderConfig
+	Piper           ProviderConfig
+	Espeak          ProviderConfig
+}
+
+// ConfigFromEnv loads TTS credentials and voices from the environment.
+//
+//	TTS_PROVIDER                                     default provider when a request names none
+//	DEEPGRAM_API_KEY, DEEPGRAM_TTS_VOICE             Aura model, e.g. aura-stella-en
+//	ELEVENLABS_API_KEY, ELEVENLABS_VOICE_ID, ELEVENLABS_MODEL
+//	OPENAI_API_KEY, OPENAI_TTS_BASE_URL, OPENAI_TTS_MODEL, OPENAI_TTS_VOICE
+//	PIPER_BINARY, PIPER_MODEL                        path to the default Piper .onnx voice
+//	PIPER_VOICES_DIR                                 where request voices (bare names) are looked up
+//	ESPEAK_BINARY, ESPEAK_VOICE
+func ConfigFromEnv() TTSConfig {
+	return TTSConfig{
+		DefaultProvider: envOr("TTS_PROVIDER", TTSDeepgram),
+		Deepgram: ProviderConfig{
+			APIKey:  os.Getenv("DEEPGRAM_API_KEY"),
+			BaseURL: envOr("DEEPGRAM_TTS_URL", "https://api.deepgram.com/v1/speak"),
+			// Stella is often more expressive and polished than Hera for 'encouraging' tones
+			Voice: envOr("DEEPGRAM_TTS_VOICE", "aura-stella-en"),
+		},
+		ElevenLabs: ProviderConfig{
+			APIKey:  os.Getenv("ELEVENLABS_API_KEY"),
+			BaseURL: envOr("ELEVENLABS_URL", "https://api.elevenlabs.io/v1"),
+			Model:   envOr("ELEVENLABS_MODEL", "eleven_multilingual_v2"),
+			Voice:   envOr("ELEVENLABS_VOICE_ID", "21m00Tcm4TlvDq8ikWAM"),
+		},
+		OpenAI: ProviderConfig{
+			APIKey:  os.Getenv("OPENAI_API_KEY"),
+			BaseURL: envOr("OPENAI_TTS_BASE_URL", "https://api.openai.com/v1"),
+			Model:   envOr("OPENAI_TTS_MODEL", "tts-1"),
+			Voice:   envOr("OPENAI_TTS_VOICE", "alloy"),
+		},
+		Piper: ProviderConfig{
+			Binary: envOr("PIPER_BINARY", "piper"),
+			Model:  os.Getenv("PIPER_MODEL"), // path to the .onnx voice
+			Voices: os.Getenv("PIPER_VOICES_DIR"),
+		},
+		Espeak: ProviderConfig{
+			Binary: envOr("ESPEAK_BINARY", "espeak-ng"),
+			Voice:  envOr("ESPEAK_VOICE", "en-us"),
+		},
+	}
+}
+
+// NewSynthesizer returns the backend named by provider, or the configured default when empty
+func NewSynthesizer(provider string, cfg TTSConfig) (Synthesizer, error) {
+	if provider == "" {
+		provider = cfg.DefaultProvider
+	}
+
+	switch strings.ToLower(provider) {
+	case TTSDeepgram:
+		return &DeepgramSynthesizer{cfg: cfg.Deepgram}, nil
+	case TTSElevenLab:
+		return &ElevenLabsSynthesizer{cfg: cfg.ElevenLabs}, nil
+	case TTSOpenAI:
+		return &OpenAISynthesizer{cfg: cfg.OpenAI}, nil
+	case TTSPiper:
+		return &PiperSynthesizer{cfg: cfg.Piper}, nil
+	case TTSEspeak:
+		return &EspeakSynthesizer{cfg: cfg.Espeak}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, provider)
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
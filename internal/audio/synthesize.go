@@ -0,0 +1,206 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"godemo/internal/models"
+	"godemo/internal/progress"
+)
+
+const (
+	DefaultTTSConcurrency = 4
+	DefaultTTSTimeoutSec  = 60
+	DefaultTTSMaxRetries  = 3
+	DefaultTTSBackoffMs   = 500
+	MaxTTSBackoff         = 30 * time.Second
+)
+
+// SynthesisConfig controls how chunks are sent to the TTS provider
+type SynthesisConfig struct {
+	Concurrency int           // chunks synthesized at once
+	Timeout     time.Duration // per attempt
+	MaxRetries  int           // retries after the first attempt, for rate limits and server errors
+	Backoff     time.Duration // wait before the first retry; doubles on each one
+}
+
+// DefaultSynthesisConfig returns the built-in synthesis limits
+func DefaultSynthesisConfig() SynthesisConfig {
+	return SynthesisConfig{
+		Concurrency: DefaultTTSConcurrency,
+		Timeout:     DefaultTTSTimeoutSec * time.Second,
+		MaxRetries:  DefaultTTSMaxRetries,
+		Backoff:     DefaultTTSBackoffMs * time.Millisecond,
+	}
+}
+
+// SynthesisConfigFromEnv overrides the defaults with TTS_CONCURRENCY, TTS_TIMEOUT_SEC,
+// TTS_MAX_RETRIES and TTS_BACKOFF_MS
+func SynthesisConfigFromEnv() SynthesisConfig {
+	cfg := DefaultSynthesisConfig()
+	if v, err := strconv.Atoi(os.Getenv("TTS_CONCURRENCY")); err == nil && v > 0 {
+		cfg.Concurrency = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("TTS_TIMEOUT_SEC"), 64); err == nil && v > 0 {
+		cfg.Timeout = time.Duration(v * float64(time.Second))
+	}
+	if v, err := strconv.Atoi(os.Getenv("TTS_MAX_RETRIES")); err == nil && v >= 0 {
+		cfg.MaxRetries = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("TTS_BACKOFF_MS")); err == nil && v >= 0 {
+		cfg.Backoff = time.Duration(v) * time.Millisecond
+	}
+	return cfg
+}
+
+// SynthesizeChunks fills AudioBytes and the measured Duration of every chunk,
+// running up to cfg.Concurrency requests at once. Synthesized chunks are returned
+// in their original order; chunks that still fail after retries are returned
+// separately so callers can report them.
+func SynthesizeChunks(
+	ctx context.Context,
+	chunks []models.AudioChunk,
+	synth Synthesizer,
+	voice string,
+	cfg SynthesisConfig,
+) ([]models.AudioChunk, []models.FailedChunk, error) {
+
+	progress.StageStarted(ctx, progress.StageSynthesize)
+	log.Printf("[TTS] Synthesizing %d chunks with %s (concurrency %d)", len(chunks), synth.Name(), cfg.Concurrency)
+
+	results := make([]*SynthesisResult, len(chunks))
+	failures := make([]*models.FailedChunk, len(chunks))
+	sem := make(chan struct{}, max(cfg.Concurrency, 1))
+	var wg sync.WaitGroup
+
+	// Workers finish out of order; count completions under a lock so the
+	// reported progress never goes backwards
+	var doneMu sync.Mutex
+	done := 0
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			result, attempts, err := synthesizeWithRetry(ctx, synth, SynthesisRequest{Text: chunk.Text, Voice: voice}, cfg)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Printf("[WARN] Failed generating audio for chunk %d after %d attempts: %v", i, attempts, err)
+				failures[i] = &models.FailedChunk{
+					Index:       i,
+					WindowIndex: chunk.WindowIndex,
+					Start:       chunk.Start,
+					End:         chunk.End,
+					Text:        chunk.Text,
+					Attempts:    attempts,
+					Error:       err.Error(),
+				}
+			} else {
+				results[i] = result
+			}
+			doneMu.Lock()
+			done++
+			progress.Chunk(ctx, progress.StageSynthesize, done, len(chunks), err)
+			doneMu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var out []models.AudioChunk
+	var failed []models.FailedChunk
+	for i, chunk := range chunks {
+		if f := failures[i]; f != nil {
+			failed = append(failed, *f)
+			continue
+		}
+		r := results[i]
+		chunk.AudioBytes = r.Audio
+		chunk.AudioFormat = r.Format
+		chunk.Duration = r.Duration
+		chunk.Words = r.Words
+		out = append(out, chunk)
+	}
+
+	if len(out) == 0 {
+		err := errors.New("no audio chunks generated")
+		if len(failed) > 0 {
+			err = fmt.Errorf("no audio chunks generated: %s", failed[0].Error)
+		}
+		progress.StageFailed(ctx, progress.StageSynthesize, err)
+		return nil, failed, err
+	}
+	progress.StageFinished(ctx, progress.StageSynthesize, fmt.Sprintf("%d/%d chunks synthesized", len(out), len(chunks)))
+
+	return out, failed, nil
+}
+
+// synthesizeWithRetry calls GenerateAudioBytes with a per-attempt timeout, backing
+// off exponentially on rate limits, server errors and timeouts. It returns the
+// number of attempts made.
+func synthesizeWithRetry(ctx context.Context, synth Synthesizer, req SynthesisRequest, cfg SynthesisConfig) (*SynthesisResult, int, error) {
+	backoff := cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		result, err := GenerateAudioBytes(attemptCtx, synth, req)
+		timedOut := errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+
+		if err == nil {
+			return result, attempt, nil
+		}
+		if ctx.Err() != nil {
+			return nil, attempt, ctx.Err()
+		}
+		if timedOut {
+			err = fmt.Errorf("timed out after %s: %w", cfg.Timeout, err)
+		}
+		if attempt > cfg.MaxRetries || !(timedOut || retryable(err)) {
+			return nil, attempt, err
+		}
+
+		wait := backoff
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > wait {
+			wait = se.RetryAfter
+		}
+		wait = min(wait, MaxTTSBackoff)
+		log.Printf("[TTS] Attempt %d failed, retrying in %s: %v", attempt, wait, err)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a synthesis error is worth another attempt
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Temporary()
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
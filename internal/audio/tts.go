@@ -2,18 +2,17 @@ package audio
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"godemo/internal/models"
+	"godemo/internal/progress"
 )
 
 const (
@@ -25,31 +24,69 @@ const (
 const (
 	TTSDeepgram  = "deepgram"
 	TTSElevenLab = "elevenlabs"
+	TTSOpenAI    = "openai"
+	TTSPiper     = "piper"
+	TTSEspeak    = "espeak"
 )
 
-// SaveFullAudio generates audio for all chunks and mixes them with background music using ffmpeg.
-func SaveFullAudio(chunks []models.AudioChunk, provider string, filename string, totalDuration float64) error {
-	dirPath := filepath.Join("instructions", "temp_audio")
+// Dir is where mixed narration tracks are written and served from
+var Dir = filepath.Join("instructions", "temp_audio")
+
+// MixAudio places synthesized chunks on the timeline over background music.
+// Chunks carrying a fit decision are time-stretched and trimmed as decided;
+// unfitted chunks are cut at the next chunk's start. Music plays at the mix's
+// bed level, ducks under narration and crossfades where its style changes.
+// Each chunk and then the whole mix are normalized to target with two-pass
+// loudnorm; the measurements are returned.
+func MixAudio(ctx context.Context, chunks []models.AudioChunk, filename string, totalDuration float64, mix models.MixSettings, target LoudnessTarget) (*models.LoudnessReport, error) {
+	dirPath := Dir
 	if err := os.MkdirAll(dirPath, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %v", err)
+		return nil, fmt.Errorf("failed to create directory: %v", err)
 	}
 
+	// Prefix temp chunks with the output name so concurrent jobs never share files
+	chunkPrefix := strings.TrimSuffix(filename, filepath.Ext(filename))
+
 	tempFiles := []string{}
+	defer func() {
+		for _, f := range tempFiles { os.Remove(f) }
+	}()
+
 	for i, chunk := range chunks {
-		audioBytes, err := GenerateAudioBytes(chunk.Text, provider)
-		if err != nil {
-			log.Printf("[WARN] Failed generating audio for chunk %d: %v", i, err)
-			continue
+		format := chunk.AudioFormat
+		if format == "" {
+			format = FormatMP3
 		}
-		tempFile := filepath.Join(dirPath, fmt.Sprintf("chunk_%d_%d.mp3", i, os.Getpid()))
-		if err := os.WriteFile(tempFile, audioBytes, 0644); err != nil {
-			return fmt.Errorf("failed to write temp chunk: %v", err)
+		tempFile := filepath.Join(dirPath, fmt.Sprintf("%s_chunk_%d.%s", chunkPrefix, i, format))
+		if err := os.WriteFile(tempFile, chunk.AudioBytes, 0644); err != nil {
+			err = fmt.Errorf("failed to write temp chunk: %v", err)
+			progress.StageFailed(ctx, progress.StageMix, err)
+			return nil, err
 		}
 		tempFiles = append(tempFiles, tempFile)
 	}
 
 	if len(tempFiles) == 0 {
-		return errors.New("no audio chunks generated")
+		return nil, errors.New("no audio chunks generated")
+	}
+
+	progress.StageStarted(ctx, progress.StageMix)
+	report := &models.LoudnessReport{TargetLUFS: target.Integrated, TruePeak: target.TruePeak}
+
+	// First loudnorm pass per chunk, so voices from different providers land at the same level
+	chunkLoudness := make([]*loudnormReport, len(tempFiles))
+	for i, f := range tempFiles {
+		m, err := measureFile(ctx, f, target)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("[WARN] Chunk %d left unnormalized: %v", i, err)
+			report.Chunks = append(report.Chunks, nil)
+			continue
+		}
+		chunkLoudness[i] = m
+		report.Chunks = append(report.Chunks, m.stats())
 	}
 
 	fullPath := filepath.Join(dirPath, filename)
@@ -78,8 +115,7 @@ func SaveFullAudio(chunks []models.AudioChunk, provider string, filename string,
 	}
 
 	var filterParts []string
-	var mixInputs []string
-	mixInputs = append(mixInputs, "[0]") // silence base
+	var voiceLabels []string
 
 	// Process Narrations (v1, v2...)
 	for i, chunk := range chunks {
@@ -96,97 +132,115 @@ func SaveFullAudio(chunks []models.AudioChunk, provider string, filename string,
 			durationLimit = (totalDuration - chunk.Start) + 0.2
 		}
 
+		// Fitted chunks already fit their slot: apply the chosen tempo and only trim when fitting gave up
+		fitFilter := fmt.Sprintf("atrim=duration=%f", durationLimit)
+		if chunk.Fit != nil {
+			fitFilter = "anull"
+			if chunk.Fit.Tempo > 0 && chunk.Fit.Tempo != 1 {
+				fitFilter = fmt.Sprintf("atempo=%f", chunk.Fit.Tempo)
+			}
+			if chunk.Fit.Trimmed {
+				fitFilter += fmt.Sprintf(",atrim=duration=%f", chunk.Duration)
+			}
+		}
+		if m := chunkLoudness[i]; m != nil {
+			fitFilter += "," + normalizeFilter(target, m, false)
+		}
+
 		// Strong broadcast voice: Volume boost + Punchy compressor + Clarity treble
-		filterParts = append(filterParts, fmt.Sprintf("[%d:a]atrim=duration=%f,volume=1.5,aresample=44100,compand=0.3|0.3:1|1:-90/-60|-60/-40|-40/-30|-20/-20:6:0:-90:0.2,treble=g=5,adelay=%d|%d[%s]", i+1, durationLimit, delayMs, delayMs, label))
-		mixInputs = append(mixInputs, fmt.Sprintf("[%s]", label))
+		filterParts = append(filterParts, fmt.Sprintf("[%d:a]%s,volume=%f,aresample=%d,compand=0.3|0.3:1|1:-90/-60|-60/-40|-40/-30|-20/-20:6:0:-90:0.2,treble=g=5,adelay=%d|%d[%s]", i+1, fitFilter, mix.VoiceVolume, MixSampleRate, delayMs, delayMs, label))
+		voiceLabels = append(voiceLabels, fmt.Sprintf("[%s]", label))
 	}
 
-	// Process Background Music segments (bg1, bg2...)
-	for i, chunk := range chunks {
-		style := chunk.MusicStyle
-		if style == "" { style = "upbeat" }
-		
-		inputIdx, ok := musicMap[style]
-		if !ok { continue }
+	// Background music, one continuous slice per run of the same style, ducked under the voice
+	musicParts, musicLabels := musicFilters(musicRuns(chunks, musicMap, totalDuration), mix, totalDuration)
+	filterParts = append(filterParts, musicParts...)
+	filterParts = append(filterParts, duckFilters(voiceLabels, musicLabels, mix)...)
 
-		label := fmt.Sprintf("bg%d", i+1)
-		duration := chunk.End - chunk.Start
-		if i < len(chunks)-1 && chunks[i+1].Start > chunk.Start {
-			duration = chunks[i+1].Start - chunk.Start
-		}
-		
-		if duration <= 0.1 { continue }
+	filterStr := strings.Join(filterParts, ";")
 
-		delayMs := int(chunk.Start * 1000)
-		// Music at 8% volume, trimmed exactly to window
-		filterParts = append(filterParts, fmt.Sprintf("[%d:a]atrim=duration=%f,volume=0.08,adelay=%d|%d[%s]", inputIdx, duration, delayMs, delayMs, label))
-		mixInputs = append(mixInputs, fmt.Sprintf("[%s]", label))
+	// Second loudnorm pass over the whole mix: measure, then render with a linear gain
+	log.Printf("[TTS] Measuring loudness of %d mixed chunks", len(tempFiles))
+	measureArgs := append(append([]string{}, args...), "-filter_complex", filterStr+";[out]"+measureFilter(target)+"[meas]", "-map", "[meas]", "-f", "null", "-")
+	stderr, err := runFFmpeg(ctx, measureArgs)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Printf("[ERROR] ffmpeg failed: %s", stderr)
+		err = fmt.Errorf("ffmpeg mix error: %v", err)
+		progress.StageFailed(ctx, progress.StageMix, err)
+		return nil, err
+	}
+	measured, err := parseLoudnorm(stderr)
+	if err != nil {
+		log.Printf("[WARN] Mix loudness not measured, normalizing in one pass: %v", err)
 	}
 
-	filterStr := strings.Join(filterParts, ";") + ";" + strings.Join(mixInputs, "") + fmt.Sprintf("amix=inputs=%d:duration=longest:dropout_transition=0:normalize=0", len(mixInputs))
-	args = append(args, "-filter_complex", filterStr, "-c:a", "libmp3lame", "-q:a", "2", fullPath)
+	finalFilter := fmt.Sprintf("%s;[out]%s,aresample=%d[final]", filterStr, normalizeFilter(target, measured, true), MixSampleRate)
+	args = append(args, "-filter_complex", finalFilter, "-map", "[final]", "-c:a", "libmp3lame", "-q:a", "2", fullPath)
 
 	log.Printf("[TTS] Mixing %d chunks into %s", len(tempFiles), fullPath)
-	
-	cmd := exec.Command("ffmpeg", args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		log.Printf("[ERROR] ffmpeg failed: %s", stderr.String())
-		return fmt.Errorf("ffmpeg mix error: %v", err)
+	stderr, err = runFFmpeg(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			os.Remove(fullPath)
+			return nil, ctx.Err()
+		}
+		log.Printf("[ERROR] ffmpeg failed: %s", stderr)
+		err = fmt.Errorf("ffmpeg mix error: %v", err)
+		progress.StageFailed(ctx, progress.StageMix, err)
+		return nil, err
+	}
+	if final, err := parseLoudnorm(stderr); err == nil {
+		report.Mix = final.stats()
 	}
 
-	for _, f := range tempFiles { os.Remove(f) }
-	return nil
+	progress.StageFinished(ctx, progress.StageMix, filename)
+	return report, nil
 }
 
-// GenerateAudioBytes calls the actual TTS provider API
-func GenerateAudioBytes(text string, provider string) ([]byte, error) {
-	if strings.TrimSpace(text) == "" {
+func runFFmpeg(ctx context.Context, args []string) (string, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return stderr.String(), err
+}
+
+// GenerateAudioBytes synthesizes text with the given provider and measures the result.
+// Results are cached by text, voice, provider and format.
+func GenerateAudioBytes(ctx context.Context, synth Synthesizer, req SynthesisRequest) (*SynthesisResult, error) {
+	if strings.TrimSpace(req.Text) == "" {
 		return nil, errors.New("empty text")
 	}
+	if req.Format == "" {
+		req.Format = FormatMP3
+	}
 
-	// Improve punctuation for more natural pauses
-	// Adding a period after an exclamation mark usually forces a better pause in Deepgram
-	text = strings.ReplaceAll(text, "!", "!. ")
-	text = strings.ReplaceAll(text, "?", "?. ")
-
-	switch provider {
-	case TTSDeepgram:
-		apiKey := "a07edc13b7ff217582930d06b86c6487a1ae6b6f"
-		// Stella is often more expressive and polished than Hera for 'encouraging' tones
-		url := "https://api.deepgram.com/v1/speak?model=aura-stella-en"
-
-		payload := map[string]string{"text": text}
-		jsonData, _ := json.Marshal(payload)
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			return nil, err
-		}
+	key := synthesisCacheKey(synth, req)
+	if result, ok := cachedSynthesis(ctx, key); ok {
+		return result, nil
+	}
 
-		req.Header.Set("Authorization", "Token "+apiKey)
-		req.Header.Set("Content-Type", "application/json")
+	result, err := synth.Synthesize(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", synth.Name(), err)
+	}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+	if result.Duration <= 0 {
+		d, err := ProbeDuration(ctx, result.Audio)
 		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("deepgram error: %s - %s", resp.Status, string(body))
+			log.Printf("[WARN] Could not measure %s audio duration: %v", synth.Name(), err)
 		}
+		result.Duration = d
+	}
 
-		return io.ReadAll(resp.Body)
-
-	case TTSElevenLab:
-		return nil, errors.New("elevenlabs not configured")
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	// Only fully measured results are reused
+	if result.Duration > 0 {
+		storeSynthesis(key, result)
 	}
+	return result, nil
 }
 
 // MapNarrationsToAudioChunks converts narration plans into time-safe audio chunk metadata.
@@ -197,7 +251,7 @@ func MapNarrationsToAudioChunks(
 ) ([]models.AudioChunk, error) {
 
 	var chunks []models.AudioChunk
-	for _, n := range narrations {
+	for i, n := range narrations {
 		if strings.TrimSpace(n.Text) == "" { continue }
 
 		duration := n.End - n.Start
@@ -205,12 +259,13 @@ func MapNarrationsToAudioChunks(
 
 		// Simple mapping directly from Gemini segments
 		chunk := models.AudioChunk{
-			WindowIndex: n.WindowIndex,
-			Start:       n.Start,
-			End:         n.End,
-			Text:        n.Text,
-			Provider:    provider,
-			MusicStyle:  n.MusicStyle,
+			NarrationIndex: i,
+			WindowIndex:    n.WindowIndex,
+			Start:          n.Start,
+			End:            n.End,
+			Text:           n.Text,
+			Provider:       provider,
+			MusicStyle:     n.MusicStyle,
 		}
 		chunks = append(chunks, chunk)
 	}
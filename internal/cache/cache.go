@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDir   = "cache"
+	DefaultMaxMB = 512
+)
+
+// Namespaces keep LLM and TTS entries apart in keys and statistics
+const (
+	NamespaceLLM = "llm"
+	NamespaceTTS = "tts"
+)
+
+// Store is a content-addressed file cache. When the total size passes maxBytes
+// the least recently used entries are removed.
+type Store struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	total   int64
+}
+
+type entry struct {
+	size int64
+	used time.Time
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore *Store
+)
+
+// Default returns the shared store configured by CACHE_DIR and CACHE_MAX_MB.
+// CACHE_MAX_MB=0 disables caching, in which case Default returns nil; a nil
+// Store misses every lookup and ignores writes.
+func Default() *Store {
+	defaultOnce.Do(func() {
+		maxMB := int64(DefaultMaxMB)
+		if v, err := strconv.ParseInt(os.Getenv("CACHE_MAX_MB"), 10, 64); err == nil && v >= 0 {
+			maxMB = v
+		}
+		if maxMB == 0 {
+			log.Println("[CACHE] Disabled (CACHE_MAX_MB=0)")
+			return
+		}
+
+		dir := os.Getenv("CACHE_DIR")
+		if dir == "" {
+			dir = DefaultDir
+		}
+		s, err := Open(dir, maxMB<<20)
+		if err != nil {
+			log.Printf("[WARN] Cache disabled: %v", err)
+			return
+		}
+		defaultStore = s
+	})
+	return defaultStore
+}
+
+// Open loads the index of an existing cache directory, creating it if needed.
+// File modification times stand in for last use across restarts.
+func Open(dir string, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	s := &Store{dir: dir, maxBytes: maxBytes, entries: make(map[string]*entry)}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %v", err)
+	}
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		s.entries[f.Name()] = &entry{size: info.Size(), used: info.ModTime()}
+		s.total += info.Size()
+	}
+
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+
+	log.Printf("[CACHE] Opened %s: %d entries, %.1f MB", dir, len(s.entries), float64(s.total)/(1<<20))
+	return s, nil
+}
+
+// Key hashes the parts of a request into a cache key within namespace
+func Key(namespace string, parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// Length prefixes keep ("ab", "c") and ("a", "bc") apart
+		fmt.Fprintf(h, "%d:%s", len(p), p)
+	}
+	return namespace + "-" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached value for key
+func (s *Store) Get(key string) ([]byte, bool) {
+	if s == nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	e, ok := s.entries[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	path := filepath.Join(s.dir, key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.remove(key)
+		return nil, false
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	e.used = now
+	s.mu.Unlock()
+	_ = os.Chtimes(path, now, now)
+
+	return data, true
+}
+
+// Put stores value under key, evicting old entries to stay within the size limit
+func (s *Store) Put(key string, value []byte) {
+	if s == nil || int64(len(value)) > s.maxBytes {
+		return
+	}
+
+	path := filepath.Join(s.dir, key)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, value, 0644); err != nil {
+		log.Printf("[WARN] Cache write failed: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		log.Printf("[WARN] Cache write failed: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.entries[key]; ok {
+		s.total -= old.size
+	}
+	s.entries[key] = &entry{size: int64(len(value)), used: time.Now()}
+	s.total += int64(len(value))
+	s.evict()
+}
+
+func (s *Store) remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		s.total -= e.size
+		delete(s.entries, key)
+	}
+}
+
+// evict deletes least recently used entries until the cache fits. Callers hold s.mu.
+func (s *Store) evict() {
+	if s.total <= s.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(s.entries))
+	for k := range s.entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return s.entries[keys[i]].used.Before(s.entries[keys[j]].used) })
+
+	for _, k := range keys {
+		if s.total <= s.maxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, k)); err != nil && !os.IsNotExist(err) {
+			log.Printf("[WARN] Cache eviction failed: %v", err)
+			continue
+		}
+		s.total -= s.entries[k].size
+		delete(s.entries, k)
+	}
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// Count is the number of lookups answered from the cache and the number that
+// had to call the provider
+type Count struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// Stats collects cache lookups made on behalf of one request
+type Stats struct {
+	mu     sync.Mutex
+	counts map[string]*Count
+}
+
+type statsKey struct{}
+
+// WithStats returns a context that records cache lookups into the returned Stats
+func WithStats(ctx context.Context) (context.Context, *Stats) {
+	s := &Stats{counts: make(map[string]*Count)}
+	return context.WithValue(ctx, statsKey{}, s), s
+}
+
+// Record counts a lookup in namespace against the Stats attached to ctx, if any
+func Record(ctx context.Context, namespace string, hit bool) {
+	s, ok := ctx.Value(statsKey{}).(*Stats)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counts[namespace]
+	if !ok {
+		c = &Count{}
+		s.counts[namespace] = c
+	}
+	if hit {
+		c.Hits++
+	} else {
+		c.Misses++
+	}
+}
+
+// Snapshot returns the counts per namespace
+func (s *Stats) Snapshot() map[string]Count {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]Count, len(s.counts))
+	for ns, c := range s.counts {
+		out[ns] = *c
+	}
+	return out
+}
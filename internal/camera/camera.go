@@ -0,0 +1,268 @@
+package camera
+
+import (
+	"math"
+	"os"
+	"strconv"
+
+	"godemo/internal/effects"
+	"godemo/internal/models"
+)
+
+// Easings
+const (
+	EaseLinear    = "linear"
+	EaseInOut     = "ease-in-out"
+	EaseOut       = "ease-out"
+	DefaultEasing = EaseInOut
+)
+
+// Keyframe reasons
+const (
+	ReasonOverview   = "overview"
+	ReasonFocus      = "focus"
+	ReasonTyping     = "typing"
+	ReasonNavigation = "navigation"
+	ReasonScroll     = "scroll"
+)
+
+const (
+	DefaultMaxPanSpeed  = 0.6 // view center travel per second, in viewport sizes
+	DefaultMaxZoomSpeed = 0.5 // scale change per second
+	DefaultTransition   = 0.8 // seconds to move into a shot
+
+	ClusterGapSec  = 3.0  // actions further apart in time start a new shot
+	ClusterMaxSpan = 0.6  // actions spread wider than this (viewport fraction) start a new shot
+	FramePadding   = 0.08 // margin around the activity, viewport fraction
+	LeadSec        = 0.3  // arrive slightly before the first action
+	HoldSec        = 1.5  // stay after the last action of a shot
+	TypingHoldSec  = 2.5  // stay longer after typing; fields usually get a result
+	OverviewGapSec = 4.0  // pull back between shots further apart than this
+)
+
+// Config limits how far and how fast the camera moves
+type Config struct {
+	MaxScale     float64
+	MaxPanSpeed  float64
+	MaxZoomSpeed float64
+	Transition   float64
+}
+
+// DefaultConfig caps zoom at the effect generator's MaxZoomScale
+func DefaultConfig() Config {
+	return Config{
+		MaxScale:     effects.MaxZoomScale,
+		MaxPanSpeed:  DefaultMaxPanSpeed,
+		MaxZoomSpeed: DefaultMaxZoomSpeed,
+		Transition:   DefaultTransition,
+	}
+}
+
+// ConfigFromEnv overrides the defaults with CAMERA_MAX_SCALE, CAMERA_MAX_PAN_SPEED
+// and CAMERA_TRANSITION_SEC
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("CAMERA_MAX_SCALE"), 64); err == nil && v >= 1 {
+		cfg.MaxScale = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("CAMERA_MAX_PAN_SPEED"), 64); err == nil && v > 0 {
+		cfg.MaxPanSpeed = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("CAMERA_TRANSITION_SEC"), 64); err == nil && v > 0 {
+		cfg.Transition = v
+	}
+	return cfg
+}
+
+// shot is a stretch of nearby actions the camera frames together
+type shot struct {
+	start, end     float64
+	x0, y0, x1, y1 float64 // union of the action bounds, viewport fractions
+	typing         bool
+}
+
+// GeneratePath computes a keyframed camera path over the video. The camera zooms
+// toward clusters of activity, holds while the user types, and pulls back to the
+// full viewport on navigation, scrolling and long pauses.
+func GeneratePath(actions []models.TimelineItem, videoDuration float64, cfg Config) []models.CameraKeyframe {
+	if videoDuration <= 0 {
+		return nil
+	}
+
+	overview := func(t float64, easing, reason string) models.CameraKeyframe {
+		return models.CameraKeyframe{T: t, X: 0.5, Y: 0.5, Scale: 1, Easing: easing, Reason: reason}
+	}
+	path := []models.CameraKeyframe{overview(0, EaseLinear, ReasonOverview)}
+	hold := func(t float64) {
+		last := path[len(path)-1]
+		if t > last.T {
+			last.T, last.Easing = t, EaseLinear
+			path = append(path, last)
+		}
+	}
+
+	var cur *shot
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		kf := frameShot(*cur, cfg)
+		arrive := math.Max(cur.start-LeadSec, 0)
+		hold(arrive - cfg.Transition)
+		kf.T = math.Max(arrive, path[len(path)-1].T)
+		path = append(path, kf)
+		hold(cur.end)
+		cur = nil
+	}
+	pullBack := func(t float64, reason string) {
+		flush()
+		if sameShot(path[len(path)-1], overview(0, "", "")) {
+			return
+		}
+		hold(t)
+		path = append(path, overview(math.Max(t, path[len(path)-1].T)+cfg.Transition, EaseOut, reason))
+	}
+
+	for _, a := range actions {
+		if a.Kind != "action" {
+			continue
+		}
+		switch a.Action {
+		case "navigation":
+			pullBack(a.T, ReasonNavigation)
+			continue
+		case "scroll":
+			pullBack(a.T, ReasonScroll)
+			continue
+		}
+
+		b := actionBounds(a)
+		if b == nil {
+			continue
+		}
+		typing := a.Action == "input"
+
+		if cur != nil {
+			x0, y0 := math.Min(cur.x0, b.X), math.Min(cur.y0, b.Y)
+			x1, y1 := math.Max(cur.x1, b.X+b.Width), math.Max(cur.y1, b.Y+b.Height)
+			if a.T-cur.end <= ClusterGapSec && x1-x0 <= ClusterMaxSpan && y1-y0 <= ClusterMaxSpan {
+				cur.x0, cur.y0, cur.x1, cur.y1 = x0, y0, x1, y1
+				cur.end = math.Max(cur.end, a.T+holdFor(typing))
+				cur.typing = cur.typing || typing
+				continue
+			}
+			end := cur.end
+			flush()
+			if a.T-end > OverviewGapSec {
+				pullBack(end, ReasonOverview)
+			}
+		}
+		cur = &shot{start: a.T, end: a.T + holdFor(typing), x0: b.X, y0: b.Y, x1: b.X + b.Width, y1: b.Y + b.Height, typing: typing}
+	}
+	flush()
+	pullBack(path[len(path)-1].T, ReasonOverview)
+
+	return limitSpeed(path, videoDuration, cfg)
+}
+
+// frameShot centers the padded activity box and zooms as far as it fits,
+// keeping the view inside the page
+func frameShot(s shot, cfg Config) models.CameraKeyframe {
+	w := s.x1 - s.x0 + 2*FramePadding
+	h := s.y1 - s.y0 + 2*FramePadding
+	scale := math.Min(cfg.MaxScale, 1/math.Max(math.Max(w, h), 1e-3))
+	scale = math.Max(scale, 1)
+
+	half := 0.5 / scale
+	x := clamp((s.x0+s.x1)/2, half, 1-half)
+	y := clamp((s.y0+s.y1)/2, half, 1-half)
+
+	reason := ReasonFocus
+	if s.typing {
+		reason = ReasonTyping
+	}
+	return models.CameraKeyframe{X: round(x), Y: round(y), Scale: round(scale), Easing: DefaultEasing, Reason: reason}
+}
+
+// limitSpeed delays keyframes whose move would exceed the pan or zoom speed
+// limits, pushing later keyframes back to keep the path in order. Keyframes
+// pushed past the end of the video are dropped and the path ends on the last state.
+func limitSpeed(path []models.CameraKeyframe, videoDuration float64, cfg Config) []models.CameraKeyframe {
+	out := []models.CameraKeyframe{path[0]}
+	for _, kf := range path[1:] {
+		prev := out[len(out)-1]
+		need := 0.0
+		if cfg.MaxPanSpeed > 0 {
+			need = math.Hypot(kf.X-prev.X, kf.Y-prev.Y) / cfg.MaxPanSpeed
+		}
+		if cfg.MaxZoomSpeed > 0 {
+			need = math.Max(need, math.Abs(kf.Scale-prev.Scale)/cfg.MaxZoomSpeed)
+		}
+
+		// Leave a hold earlier when it has room, so the move starts sooner
+		// rather than arriving late
+		if n := len(out); n >= 2 && kf.T-prev.T < need && sameShot(out[n-2], prev) {
+			out[n-1].T = round(math.Max(out[n-2].T, kf.T-need))
+			if out[n-1].T == out[n-2].T {
+				out = out[:n-1] // the hold is used up
+			}
+			prev = out[len(out)-1]
+		}
+		kf.T = round(math.Max(kf.T, prev.T+need))
+		if kf.T > videoDuration {
+			break
+		}
+		if kf.T == prev.T && sameShot(kf, prev) {
+			continue
+		}
+		out = append(out, kf)
+	}
+
+	if last := out[len(out)-1]; last.T < videoDuration {
+		last.T, last.Easing = videoDuration, EaseLinear
+		out = append(out, last)
+	}
+	return out
+}
+
+// actionBounds returns the action's bounds as viewport fractions, clipped to the viewport
+func actionBounds(a models.TimelineItem) *models.BoundingBox {
+	b := a.ViewportBounds
+	if b == nil && a.Bounds != nil && a.Viewport != nil && a.Viewport.Width > 0 && a.Viewport.Height > 0 {
+		b = &models.BoundingBox{
+			X:      a.Bounds.X / a.Viewport.Width,
+			Y:      a.Bounds.Y / a.Viewport.Height,
+			Width:  a.Bounds.Width / a.Viewport.Width,
+			Height: a.Bounds.Height / a.Viewport.Height,
+		}
+	}
+	if b == nil {
+		return nil
+	}
+
+	x0, y0 := clamp(b.X, 0, 1), clamp(b.Y, 0, 1)
+	x1, y1 := clamp(b.X+b.Width, 0, 1), clamp(b.Y+b.Height, 0, 1)
+	if x1 <= x0 || y1 <= y0 {
+		return nil // off screen
+	}
+	return &models.BoundingBox{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+func sameShot(a, b models.CameraKeyframe) bool {
+	return a.X == b.X && a.Y == b.Y && a.Scale == b.Scale
+}
+
+func holdFor(typing bool) float64 {
+	if typing {
+		return TypingHoldSec
+	}
+	return HoldSec
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Min(math.Max(v, lo), hi)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,235 @@
+package captions
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+const (
+	DefaultMaxCharsPerLine = 42 // broadcast subtitle convention
+	DefaultMaxLines        = 2
+	DefaultMinCueSec       = 1.0 // shorter cues flash by unread
+	MaxWordGapSec          = 1.0 // a pause this long starts a new cue
+)
+
+// Dir is where caption files are written and served from
+var Dir = filepath.Join("instructions", "captions")
+
+// Config controls how narration text is split into cues
+type Config struct {
+	MaxCharsPerLine int
+	MaxLines        int
+	MinCueSec       float64
+}
+
+// DefaultConfig returns conventional subtitle limits
+func DefaultConfig() Config {
+	return Config{
+		MaxCharsPerLine: DefaultMaxCharsPerLine,
+		MaxLines:        DefaultMaxLines,
+		MinCueSec:       DefaultMinCueSec,
+	}
+}
+
+// Cue is one subtitle shown on screen
+type Cue struct {
+	Start float64  `json:"start"`
+	End   float64  `json:"end"`
+	Lines []string `json:"lines"`
+}
+
+// BuildCues splits narrations into readable cues.
+// When a narration's audio chunk carries word timings the cues follow the real speech;
+// otherwise the narration's time span is shared out by character count. Chunks are
+// paired with narrations by NarrationIndex, since merged windows hold several.
+func BuildCues(narrations []models.Narration, chunks []models.AudioChunk, cfg Config) []Cue {
+	byNarration := make(map[int]models.AudioChunk, len(chunks))
+	for _, c := range chunks {
+		byNarration[c.NarrationIndex] = c
+	}
+
+	var cues []Cue
+	for i, n := range narrations {
+		chunk, hasChunk := byNarration[i]
+
+		switch {
+		case hasChunk && len(chunk.Words) > 0:
+			cues = append(cues, timedCues(chunk, cfg)...)
+		case hasChunk && chunk.Duration > 0:
+			// Audio was fitted: follow where it actually plays
+			cues = append(cues, spreadCues(chunk.Text, chunk.Start, chunk.Start+chunk.Duration, cfg)...)
+		case strings.TrimSpace(n.Text) != "":
+			cues = append(cues, spreadCues(n.Text, n.Start, n.End, cfg)...)
+		}
+	}
+
+	enforceMinDuration(cues, cfg.MinCueSec)
+	return cues
+}
+
+// timedCues groups TTS word timings into cues, mapping them onto the chunk's placement
+func timedCues(chunk models.AudioChunk, cfg Config) []Cue {
+	tempo := 1.0
+	if chunk.Fit != nil && chunk.Fit.Tempo > 0 {
+		tempo = chunk.Fit.Tempo
+	}
+	limit := math.Inf(1)
+	if chunk.Duration > 0 {
+		limit = chunk.Start + chunk.Duration
+	}
+
+	var cues []Cue
+	var words []string
+	var start, end float64
+
+	flush := func() {
+		if len(words) > 0 {
+			cues = append(cues, Cue{Start: start, End: end, Lines: wrap(words, cfg.MaxCharsPerLine)})
+		}
+		words = nil
+	}
+
+	for _, w := range chunk.Words {
+		ws := chunk.Start + w.Start/tempo
+		we := math.Min(chunk.Start+w.End/tempo, limit)
+		if ws >= limit {
+			break // trimmed away by duration fitting
+		}
+
+		if len(words) > 0 {
+			candidate := append(append([]string(nil), words...), w.Word)
+			tooLong := len(wrap(candidate, cfg.MaxCharsPerLine)) > cfg.MaxLines
+			if tooLong || ws-end > MaxWordGapSec || endsSentence(words[len(words)-1]) {
+				flush()
+			}
+		}
+
+		if len(words) == 0 {
+			start = ws
+		}
+		words = append(words, w.Word)
+		end = we
+	}
+	flush()
+
+	return cues
+}
+
+// spreadCues splits text into cue-sized blocks and shares start–end between them by length
+func spreadCues(text string, start, end float64, cfg Config) []Cue {
+	var blocks [][]string
+	var current []string
+
+	for _, word := range strings.Fields(text) {
+		candidate := append(append([]string(nil), current...), word)
+		if len(current) > 0 && (len(wrap(candidate, cfg.MaxCharsPerLine)) > cfg.MaxLines || endsSentence(current[len(current)-1])) {
+			blocks = append(blocks, current)
+			current = nil
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, current)
+	}
+
+	total := 0
+	for _, b := range blocks {
+		total += len(strings.Join(b, " "))
+	}
+	if total == 0 || end <= start {
+		return nil
+	}
+
+	var cues []Cue
+	t := start
+	for _, b := range blocks {
+		d := (end - start) * float64(len(strings.Join(b, " "))) / float64(total)
+		cues = append(cues, Cue{Start: t, End: t + d, Lines: wrap(b, cfg.MaxCharsPerLine)})
+		t += d
+	}
+	return cues
+}
+
+// wrap greedily packs words into lines of at most maxChars
+func wrap(words []string, maxChars int) []string {
+	var lines []string
+	line := ""
+	for _, w := range words {
+		if line != "" && len(line)+1+len(w) > maxChars {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += w
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// enforceMinDuration stretches short cues into the gap before the next cue
+func enforceMinDuration(cues []Cue, minSec float64) {
+	for i := range cues {
+		if cues[i].End-cues[i].Start >= minSec {
+			continue
+		}
+		target := cues[i].Start + minSec
+		if i < len(cues)-1 {
+			target = math.Min(target, cues[i+1].Start)
+		}
+		cues[i].End = math.Max(cues[i].End, target)
+	}
+}
+
+func endsSentence(word string) bool {
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+}
+
+// FormatSRT renders cues as SubRip
+func FormatSRT(cues []Cue) string {
+	var b strings.Builder
+	for i, c := range cues {
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(c.Start, ","), timestamp(c.End, ","), strings.Join(c.Lines, "\n"))
+	}
+	return b.String()
+}
+
+// FormatVTT renders cues as WebVTT
+func FormatVTT(cues []Cue) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", timestamp(c.Start, "."), timestamp(c.End, "."), strings.Join(c.Lines, "\n"))
+	}
+	return b.String()
+}
+
+// timestamp formats seconds as HH:MM:SS<sep>mmm
+func timestamp(sec float64, sep string) string {
+	ms := int64(math.Round(math.Max(sec, 0) * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// WriteFiles saves <session>.srt and <session>.vtt under Dir
+func WriteFiles(sessionID string, cues []Cue) error {
+	name := models.SessionFileName(sessionID)
+	if name == "" {
+		return fmt.Errorf("invalid session ID %q", sessionID)
+	}
+	if err := os.MkdirAll(Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create captions directory: %v", err)
+	}
+	base := filepath.Join(Dir, name)
+	if err := os.WriteFile(base+".srt", []byte(FormatSRT(cues)), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(base+".vtt", []byte(FormatVTT(cues)), 0644)
+}
@@ -0,0 +1,49 @@
+package captions
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"godemo/internal/models"
+)
+
+func TestBuildCuesMergedWindow(t *testing.T) {
+	// Repair merged both narrations' windows into window 0
+	narrations := []models.Narration{
+		{WindowIndex: 0, Start: 0, End: 3, Text: "First we open the settings."},
+		{WindowIndex: 0, Start: 3, End: 6, Text: "Then we save the form."},
+	}
+	chunks := []models.AudioChunk{
+		{NarrationIndex: 0, WindowIndex: 0, Start: 0, End: 3, Duration: 2.5, Text: narrations[0].Text},
+		{NarrationIndex: 1, WindowIndex: 0, Start: 3, End: 6, Duration: 2.5, Text: narrations[1].Text},
+	}
+
+	var got []string
+	for _, c := range BuildCues(narrations, chunks, DefaultConfig()) {
+		got = append(got, strings.Join(c.Lines, " "))
+	}
+	want := []string{narrations[0].Text, narrations[1].Text}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cues = %q, want %q", got, want)
+	}
+}
+
+func TestBuildCuesFailedChunk(t *testing.T) {
+	// The first chunk failed synthesis; its narration falls back to its own span
+	narrations := []models.Narration{
+		{WindowIndex: 0, Start: 0, End: 3, Text: "First we open the settings."},
+		{WindowIndex: 0, Start: 3, End: 6, Text: "Then we save the form."},
+	}
+	chunks := []models.AudioChunk{
+		{NarrationIndex: 1, WindowIndex: 0, Start: 3.2, End: 6, Duration: 2, Text: narrations[1].Text},
+	}
+
+	cues := BuildCues(narrations, chunks, DefaultConfig())
+	if len(cues) != 2 {
+		t.Fatalf("got %d cues, want 2", len(cues))
+	}
+	if cues[0].Start != 0 || cues[1].Start != 3.2 {
+		t.Errorf("cue starts = %v, %v; want 0, 3.2", cues[0].Start, cues[1].Start)
+	}
+}
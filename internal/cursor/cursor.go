@@ -0,0 +1,238 @@
+package cursor
+
+import (
+	"math"
+	"sort"
+
+	"godemo/internal/models"
+)
+
+// Track sources
+const (
+	SourceRecorded    = "recorded"
+	SourceSynthesized = "synthesized"
+)
+
+// Easings
+const (
+	EaseLinear = "linear"
+	EaseIn     = "ease-in"
+	EaseOut    = "ease-out"
+)
+
+const (
+	MinRecordedSamples = 2
+	SimplifyTolerance  = 0.004 // max deviation of the simplified path, viewport fraction
+
+	DwellSec       = 0.25 // pointer rests on the target before a click
+	TypingDwellSec = 0.15
+	SettleSec      = 0.2  // pause after an action before moving on
+	MoveBaseSec    = 0.35 // shortest move
+	MoveSecPerUnit = 0.6  // extra time per viewport size travelled
+	MinMoveSec     = 0.15
+	MaxMoveSec     = 1.2
+	ArcRatio       = 0.12 // sideways bow of a move, relative to its length
+
+	RippleSec = 0.5
+)
+
+// GenerateTrack builds the pointer path over the video. Recorded mousemove
+// samples are used when there are enough of them, simplified to the points that
+// shape the path. Otherwise a path is synthesized between the targets of
+// consecutive actions, arriving slightly before each one and easing in and out
+// along a gentle arc. Clicks are marked on the track either way.
+func GenerateTrack(actions []models.TimelineItem, samples []models.CursorPoint, videoDuration float64) models.CursorTrack {
+	if len(samples) >= MinRecordedSamples {
+		points := append([]models.CursorPoint(nil), samples...)
+		sort.SliceStable(points, func(i, j int) bool { return points[i].T < points[j].T })
+		points = simplify(points, SimplifyTolerance)
+		for i := range points {
+			points[i].Easing = EaseLinear
+		}
+		return models.CursorTrack{Source: SourceRecorded, Points: markClicks(points, actions)}
+	}
+	return models.CursorTrack{Source: SourceSynthesized, Points: synthesize(actions, videoDuration)}
+}
+
+// ClickEffects emits a ripple at every click so replays show where the pointer pressed
+func ClickEffects(actions []models.TimelineItem, videoDuration float64) []models.DisplayEffect {
+	var fx []models.DisplayEffect
+	for _, a := range actions {
+		if a.Kind != "action" || a.Action != "click" || a.Bounds == nil || a.Bounds.Width <= 0 || a.Bounds.Height <= 0 {
+			continue
+		}
+		end := math.Min(a.T+RippleSec, videoDuration)
+		if end <= a.T {
+			continue
+		}
+		selector, _ := a.Target["selector"].(string)
+		fx = append(fx, models.DisplayEffect{
+			Start: a.T,
+			End:   end,
+			Type:  "click",
+			Target: &models.EffectTarget{
+				Selector: selector,
+				Bounds:   a.Bounds,
+
+				ViewportBounds: a.ViewportBounds,
+			},
+			Style: map[string]interface{}{
+				"ripple": true,
+				"color":  "#ffffff",
+			},
+		})
+	}
+	return fx
+}
+
+// synthesize moves the pointer from the middle of the page to each action target in turn
+func synthesize(actions []models.TimelineItem, videoDuration float64) []models.CursorPoint {
+	points := []models.CursorPoint{{T: 0, X: 0.5, Y: 0.5, Easing: EaseLinear}}
+	free := 0.0 // earliest time the pointer may leave its current spot
+
+	for _, a := range actions {
+		x, y, ok := targetPoint(a)
+		if a.Kind != "action" || !ok || a.T > videoDuration {
+			continue
+		}
+		prev := points[len(points)-1]
+		dwell := DwellSec
+		if a.Action == "input" {
+			dwell = TypingDwellSec
+		}
+
+		dist := math.Hypot(x-prev.X, y-prev.Y)
+		if dist > 1e-3 {
+			arrive := math.Max(a.T-dwell, free)
+			depart := math.Max(arrive-math.Min(MoveBaseSec+dist*MoveSecPerUnit, MaxMoveSec), free)
+			// Actions in quick succession leave no room; arrive a little late rather than jump
+			arrive = math.Max(arrive, depart+MinMoveSec)
+			if depart > prev.T {
+				points = append(points, models.CursorPoint{T: round(depart), X: prev.X, Y: prev.Y, Easing: EaseLinear})
+			}
+
+			// Bow the path sideways through its midpoint, like a wrist turning
+			mx := (prev.X+x)/2 - (y-prev.Y)*ArcRatio
+			my := (prev.Y+y)/2 + (x-prev.X)*ArcRatio
+			points = append(points,
+				models.CursorPoint{T: round((depart + arrive) / 2), X: round(clampUnit(mx)), Y: round(clampUnit(my)), Easing: EaseIn},
+				models.CursorPoint{T: round(arrive), X: round(x), Y: round(y), Easing: EaseOut},
+			)
+		}
+
+		if a.Action == "click" {
+			t := math.Max(a.T, points[len(points)-1].T)
+			points = append(points, models.CursorPoint{T: round(t), X: round(x), Y: round(y), Easing: EaseLinear, Click: true})
+		}
+		free = math.Max(a.T, points[len(points)-1].T) + SettleSec
+	}
+
+	if last := points[len(points)-1]; last.T < videoDuration {
+		last.T, last.Easing, last.Click = videoDuration, EaseLinear, false
+		points = append(points, last)
+	}
+	return dedupe(points)
+}
+
+// markClicks inserts a click point at each click, positioned on the recorded path
+func markClicks(points []models.CursorPoint, actions []models.TimelineItem) []models.CursorPoint {
+	for _, a := range actions {
+		if a.Kind != "action" || a.Action != "click" {
+			continue
+		}
+		i := sort.Search(len(points), func(i int) bool { return points[i].T >= a.T })
+		if i < len(points) && points[i].T == a.T {
+			points[i].Click = true
+			continue
+		}
+		p := positionAt(points, i, a.T)
+		p.Click, p.Easing = true, EaseLinear
+		points = append(points[:i], append([]models.CursorPoint{p}, points[i:]...)...)
+	}
+	return points
+}
+
+// positionAt interpolates the path at t, where i is the first point at or after t
+func positionAt(points []models.CursorPoint, i int, t float64) models.CursorPoint {
+	switch {
+	case i == 0:
+		return models.CursorPoint{T: t, X: points[0].X, Y: points[0].Y}
+	case i == len(points):
+		last := points[i-1]
+		return models.CursorPoint{T: t, X: last.X, Y: last.Y}
+	}
+	a, b := points[i-1], points[i]
+	f := 0.0
+	if b.T > a.T {
+		f = (t - a.T) / (b.T - a.T)
+	}
+	return models.CursorPoint{T: t, X: round(a.X + (b.X-a.X)*f), Y: round(a.Y + (b.Y-a.Y)*f)}
+}
+
+// simplify reduces a recorded path with Douglas-Peucker, measuring each point
+// against where the pointer would be at that time on the simplified segment,
+// so pauses survive as well as corners
+func simplify(points []models.CursorPoint, tolerance float64) []models.CursorPoint {
+	if len(points) <= 2 {
+		return points
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	var walk func(lo, hi int)
+	walk = func(lo, hi int) {
+		worst, worstAt := 0.0, -1
+		for i := lo + 1; i < hi; i++ {
+			p := positionAt([]models.CursorPoint{points[lo], points[hi]}, 1, points[i].T)
+			if d := math.Hypot(points[i].X-p.X, points[i].Y-p.Y); d > worst {
+				worst, worstAt = d, i
+			}
+		}
+		if worstAt >= 0 && worst > tolerance {
+			keep[worstAt] = true
+			walk(lo, worstAt)
+			walk(worstAt, hi)
+		}
+	}
+	walk(0, len(points)-1)
+
+	var out []models.CursorPoint
+	for i, p := range points {
+		if keep[i] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// targetPoint is the center of the action's bounds as viewport fractions
+func targetPoint(a models.TimelineItem) (float64, float64, bool) {
+	if b := a.ViewportBounds; b != nil {
+		return clampUnit(b.X + b.Width/2), clampUnit(b.Y + b.Height/2), true
+	}
+	if b, vp := a.Bounds, a.Viewport; b != nil && vp != nil && vp.Width > 0 && vp.Height > 0 && b.Width > 0 {
+		return clampUnit((b.X + b.Width/2) / vp.Width), clampUnit((b.Y + b.Height/2) / vp.Height), true
+	}
+	return 0, 0, false
+}
+
+// dedupe drops points that repeat the previous time and position
+func dedupe(points []models.CursorPoint) []models.CursorPoint {
+	out := points[:1]
+	for _, p := range points[1:] {
+		last := out[len(out)-1]
+		if p.T <= last.T && p.X == last.X && p.Y == last.Y && !p.Click {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func clampUnit(v float64) float64 {
+	return math.Min(math.Max(v, 0), 1)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
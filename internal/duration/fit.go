@@ -1,3 +1,185 @@
 package duration
 
-// Duration fitting functions
+import (
+	"context"
+	"log"
+	"math"
+	"os"
+	"strconv"
+
+	"godemo/internal/models"
+)
+
+// Fitting strategies, in the order they are tried
+const (
+	StrategyNone    = "none"    // audio already fits before the next chunk
+	StrategyShift   = "shift"   // start moved earlier into preceding silence
+	StrategyStretch = "stretch" // sped up with atempo within MaxTempo
+	StrategyRewrite = "rewrite" // LLM produced shorter text that was re-synthesized
+	StrategyTrim    = "trim"    // nothing else fit: max tempo, then cut
+)
+
+const (
+	DefaultMaxTempo    = 1.25 // beyond this speech starts sounding rushed
+	DefaultMaxShiftSec = 1.5  // how far a chunk may start before its planned time
+	DefaultMaxRewrites = 1
+	MinGapSec          = 0.05 // breathing room kept between consecutive chunks
+)
+
+// Config bounds how aggressively chunks may be altered to fit
+type Config struct {
+	MaxTempo    float64
+	MaxShiftSec float64
+	MaxRewrites int
+}
+
+// DefaultConfig returns the built-in fitting limits
+func DefaultConfig() Config {
+	return Config{
+		MaxTempo:    DefaultMaxTempo,
+		MaxShiftSec: DefaultMaxShiftSec,
+		MaxRewrites: DefaultMaxRewrites,
+	}
+}
+
+// ConfigFromEnv overrides the defaults with FIT_MAX_TEMPO, FIT_MAX_SHIFT_SEC and FIT_MAX_REWRITES
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("FIT_MAX_TEMPO"), 64); err == nil && v >= 1 {
+		cfg.MaxTempo = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("FIT_MAX_SHIFT_SEC"), 64); err == nil && v >= 0 {
+		cfg.MaxShiftSec = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("FIT_MAX_REWRITES")); err == nil && v >= 0 {
+		cfg.MaxRewrites = v
+	}
+	return cfg
+}
+
+// Rewriter returns a re-synthesized chunk whose text should speak in at most maxDuration seconds.
+// The returned chunk must carry its new AudioBytes and measured Duration.
+type Rewriter func(ctx context.Context, chunk models.AudioChunk, maxDuration float64) (models.AudioChunk, error)
+
+// FitChunks makes every synthesized chunk end before the next one starts.
+// Chunks are processed in order so each can use silence left by the previous one.
+// Chunks without a measured Duration are returned untouched. rewrite may be nil.
+func FitChunks(
+	ctx context.Context,
+	chunks []models.AudioChunk,
+	videoDuration float64,
+	cfg Config,
+	rewrite Rewriter,
+) []models.AudioChunk {
+
+	out := make([]models.AudioChunk, len(chunks))
+	copy(out, chunks)
+
+	prevEnd := 0.0
+	for i := range out {
+		slotEnd := videoDuration
+		if i < len(out)-1 && out[i+1].Start > out[i].Start {
+			slotEnd = out[i+1].Start - MinGapSec
+		}
+
+		if out[i].Duration <= 0 {
+			// Unknown length (ffprobe unavailable): leave it to the mixer's hard cut
+			prevEnd = math.Min(out[i].End, slotEnd)
+			continue
+		}
+
+		out[i] = fitChunk(ctx, out[i], prevEnd, slotEnd, cfg, rewrite)
+		prevEnd = out[i].Start + out[i].Duration
+
+		log.Printf("[FIT] Chunk %d: %s (raw %.2fs, slot %.2fs, tempo %.2f, shift %.2fs)",
+			i, out[i].Fit.Strategy, out[i].Fit.RawDuration, out[i].Fit.SlotDuration, out[i].Fit.Tempo, out[i].Fit.ShiftSec)
+	}
+
+	return out
+}
+
+// fitChunk picks the least destructive strategy that makes chunk fit between prevEnd and slotEnd
+func fitChunk(
+	ctx context.Context,
+	chunk models.AudioChunk,
+	prevEnd, slotEnd float64,
+	cfg Config,
+	rewrite Rewriter,
+) models.AudioChunk {
+
+	earliest := math.Max(math.Max(prevEnd+MinGapSec, chunk.Start-cfg.MaxShiftSec), 0)
+	if earliest > chunk.Start {
+		earliest = chunk.Start
+	}
+
+	fit := &models.FitDecision{
+		RawDuration:  chunk.Duration,
+		SlotDuration: math.Max(slotEnd-chunk.Start, 0),
+		Tempo:        1,
+	}
+
+	for {
+		raw := chunk.Duration
+
+		// 1. Fits as planned
+		if raw <= slotEnd-chunk.Start {
+			fit.Strategy = pick(fit, StrategyNone)
+			return place(chunk, chunk.Start, raw, fit)
+		}
+
+		// 2. Start earlier, using only as much silence as needed
+		if raw <= slotEnd-earliest {
+			start := slotEnd - raw
+			fit.ShiftSec = chunk.Start - start
+			fit.Strategy = pick(fit, StrategyShift)
+			return place(chunk, start, raw, fit)
+		}
+
+		// 3. Use all available silence and speed up the remainder
+		avail := slotEnd - earliest
+		if avail > 0 && raw/avail <= cfg.MaxTempo {
+			fit.ShiftSec = chunk.Start - earliest
+			fit.Tempo = raw / avail
+			fit.Strategy = pick(fit, StrategyStretch)
+			return place(chunk, earliest, avail, fit)
+		}
+
+		// 4. Ask for shorter text and try again
+		if rewrite == nil || fit.Rewrites >= cfg.MaxRewrites || ctx.Err() != nil {
+			break
+		}
+		rewritten, err := rewrite(ctx, chunk, avail*cfg.MaxTempo)
+		if err != nil || rewritten.Duration <= 0 || rewritten.Duration >= raw {
+			log.Printf("[FIT] Rewrite did not shorten chunk %d: %v", chunk.WindowIndex, err)
+			break
+		}
+		chunk = rewritten
+		fit.Rewrites++
+	}
+
+	// 5. Last resort: max tempo and cut whatever still overflows
+	avail := math.Max(slotEnd-earliest, 0)
+	fit.ShiftSec = chunk.Start - earliest
+	fit.Tempo = cfg.MaxTempo
+	fit.Trimmed = chunk.Duration/cfg.MaxTempo > avail
+	fit.Strategy = StrategyTrim
+	return place(chunk, earliest, math.Min(chunk.Duration/cfg.MaxTempo, avail), fit)
+}
+
+// pick reports a rewrite as the strategy when one was needed to reach the final fit
+func pick(fit *models.FitDecision, strategy string) string {
+	if fit.Rewrites > 0 {
+		return StrategyRewrite
+	}
+	return strategy
+}
+
+// place moves the chunk to start and records its played duration.
+// End keeps the narration's planned end unless the audio now runs past it.
+func place(chunk models.AudioChunk, start, played float64, fit *models.FitDecision) models.AudioChunk {
+	chunk.Start = start
+	chunk.Duration = played
+	chunk.End = math.Max(chunk.End, start+played)
+	chunk.Fit = fit
+	return chunk
+}
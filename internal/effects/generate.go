@@ -130,6 +130,8 @@ func effectForClick(
 		Target: &models.EffectTarget{
 			Selector: extractSelector(action),
 			Bounds:   action.Bounds,
+
+			ViewportBounds: action.ViewportBounds,
 		},
 		Style: map[string]interface{}{
 			"outline":       "glow",
@@ -160,6 +162,8 @@ func effectForInput(
 		Target: &models.EffectTarget{
 			Selector: extractSelector(action),
 			Bounds:   action.Bounds,
+
+			ViewportBounds: action.ViewportBounds,
 		},
 		Style: map[string]interface{}{
 			"borderColor":   "#0066ff",
@@ -212,6 +216,8 @@ func effectForHover(
 		Target: &models.EffectTarget{
 			Selector: extractSelector(action),
 			Bounds:   action.Bounds,
+
+			ViewportBounds: action.ViewportBounds,
 		},
 		Style: map[string]interface{}{
 			"outline": "soft",
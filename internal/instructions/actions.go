@@ -125,6 +125,21 @@ func extractMetadata(a models.TimelineItem) map[string]interface{} {
 		}
 	}
 
+	if a.URL != "" {
+		meta["url"] = a.URL
+	}
+	if a.Action == "navigation" && a.Target != nil {
+		for _, k := range []string{"from", "title", "label"} {
+			if v, ok := a.Target[k]; ok {
+				meta[k] = v
+			}
+		}
+	}
+	if a.Scroll != nil && a.Scroll.Direction != "none" {
+		meta["scrollDirection"] = a.Scroll.Direction
+		meta["scrollDistance"] = a.Scroll.Distance
+	}
+
 	if len(meta) == 0 {
 		return nil
 	}
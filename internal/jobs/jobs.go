@@ -0,0 +1,319 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"godemo/internal/progress"
+)
+
+// Job lifecycle states
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateFailed    State = "failed"
+	StateDone      State = "done"
+	StateCancelled State = "cancelled"
+)
+
+var (
+	ErrQueueFull   = errors.New("job queue is full")
+	ErrNotFound    = errors.New("job not found")
+	ErrUnknownType = errors.New("unknown job type")
+	ErrFinished    = errors.New("job already finished")
+)
+
+// Handler runs a single job. It must stop promptly once ctx is cancelled.
+type Handler func(ctx context.Context, job *Job) (interface{}, error)
+
+// Job is a unit of pipeline work tracked by the Manager
+type Job struct {
+	mu sync.Mutex
+
+	id         string
+	jobType    string
+	payload    interface{}
+	state      State
+	stage      string
+	err        string
+	result     interface{}
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	cancel     context.CancelFunc
+	events     *progress.Stream
+}
+
+// Status is the JSON view of a job returned by the status endpoint
+type Status struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	State      State       `json:"state"`
+	Stage      string      `json:"stage,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// ID returns the job identifier
+func (j *Job) ID() string { return j.id }
+
+// Type returns the job type the job was submitted with
+func (j *Job) Type() string { return j.jobType }
+
+// Payload returns the value the job was submitted with
+func (j *Job) Payload() interface{} { return j.payload }
+
+// Events returns the job's progress stream
+func (j *Job) Events() *progress.Stream { return j.events }
+
+// SetStage records the pipeline stage the job is currently in
+func (j *Job) SetStage(stage string) {
+	j.mu.Lock()
+	j.stage = stage
+	j.mu.Unlock()
+}
+
+// Status returns a consistent snapshot of the job
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := Status{
+		ID:        j.id,
+		Type:      j.jobType,
+		State:     j.state,
+		Stage:     j.stage,
+		Error:     j.err,
+		Result:    j.result,
+		CreatedAt: j.createdAt,
+	}
+	if !j.startedAt.IsZero() {
+		t := j.startedAt
+		s.StartedAt = &t
+	}
+	if !j.finishedAt.IsZero() {
+		t := j.finishedAt
+		s.FinishedAt = &t
+	}
+	return s
+}
+
+// finished reports whether the job reached a terminal state
+func (j *Job) finished() bool {
+	return j.state == StateDone || j.state == StateFailed || j.state == StateCancelled
+}
+
+// Manager queues jobs and runs them on a fixed pool of workers
+type Manager struct {
+	mu       sync.RWMutex
+	jobs     map[string]*Job
+	handlers map[string]Handler
+	queue    chan *Job
+	ttl      time.Duration
+}
+
+// NewManager starts a manager with the given number of workers and queue capacity.
+// Finished jobs are forgotten after ttl (zero keeps them forever).
+func NewManager(workers, queueSize int, ttl time.Duration) *Manager {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	m := &Manager{
+		jobs:     make(map[string]*Job),
+		handlers: make(map[string]Handler),
+		queue:    make(chan *Job, queueSize),
+		ttl:      ttl,
+	}
+
+	for i := 0; i < workers; i++ {
+		go m.worker(i + 1)
+	}
+
+	log.Printf("[JOBS] Started %d workers (queue size %d)", workers, queueSize)
+	return m
+}
+
+// Handle registers the handler for a job type. It must be called before Submit.
+func (m *Manager) Handle(jobType string, h Handler) {
+	m.mu.Lock()
+	m.handlers[jobType] = h
+	m.mu.Unlock()
+}
+
+// Submit enqueues a new job and returns it without waiting for it to run
+func (m *Manager) Submit(jobType string, payload interface{}) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+	}
+
+	m.pruneLocked()
+
+	job := &Job{
+		id:        newID(),
+		jobType:   jobType,
+		payload:   payload,
+		state:     StateQueued,
+		createdAt: time.Now(),
+		events:    progress.NewStream(),
+	}
+
+	select {
+	case m.queue <- job:
+	default:
+		return nil, ErrQueueFull
+	}
+
+	m.jobs[job.id] = job
+	log.Printf("[JOBS] Queued %s job %s", jobType, job.id)
+	return job, nil
+}
+
+// Get looks up a job by ID
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// Cancel stops a queued or running job. The job record is kept so its
+// final state can still be polled.
+func (m *Manager) Cancel(id string) (*Job, error) {
+	job, ok := m.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	if job.finished() {
+		return job, ErrFinished
+	}
+
+	switch job.state {
+	case StateQueued:
+		// Worker will skip it when dequeued
+		job.state = StateCancelled
+		job.finishedAt = time.Now()
+		job.publishDone()
+	case StateRunning:
+		// Worker records the final state once the handler returns
+		job.cancel()
+	}
+
+	log.Printf("[JOBS] Cancellation requested for job %s", id)
+	return job, nil
+}
+
+func (m *Manager) worker(n int) {
+	for job := range m.queue {
+		m.run(n, job)
+	}
+}
+
+func (m *Manager) run(n int, job *Job) {
+	m.mu.RLock()
+	handler := m.handlers[job.jobType]
+	m.mu.RUnlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Pipeline progress feeds both the polled stage and the event stream
+	ctx = progress.WithEmitter(ctx, func(ev progress.Event) {
+		if ev.Type == progress.EventStageStarted {
+			job.SetStage(ev.Stage)
+		}
+		job.events.Publish(ev)
+	})
+
+	job.mu.Lock()
+	if job.state == StateCancelled {
+		job.mu.Unlock()
+		return
+	}
+	job.state = StateRunning
+	job.startedAt = time.Now()
+	job.cancel = cancel
+	job.mu.Unlock()
+
+	log.Printf("[JOBS] Worker %d running job %s", n, job.id)
+	result, err := runSafely(ctx, handler, job)
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.finishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		job.state = StateCancelled
+		job.err = "cancelled"
+	case err != nil:
+		job.state = StateFailed
+		job.err = err.Error()
+	default:
+		job.state = StateDone
+		job.result = result
+	}
+	job.publishDone()
+
+	log.Printf("[JOBS] Job %s finished: %s (%.1fs)", job.id, job.state, job.finishedAt.Sub(job.startedAt).Seconds())
+}
+
+// publishDone emits the terminal event and closes the stream. Caller holds j.mu.
+func (j *Job) publishDone() {
+	j.events.Publish(progress.Event{Type: progress.EventDone, Stage: j.stage, Message: string(j.state), Error: j.err})
+	j.events.Close()
+}
+
+// runSafely turns a handler panic into a job failure instead of killing the worker
+func runSafely(ctx context.Context, h Handler, job *Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// pruneLocked drops finished jobs older than the manager TTL
+func (m *Manager) pruneLocked() {
+	if m.ttl <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-m.ttl)
+	for id, job := range m.jobs {
+		job.mu.Lock()
+		expired := job.finished() && job.finishedAt.Before(cutoff)
+		job.mu.Unlock()
+		if expired {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "job_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	AnthropicAPIVersion = "2023-06-01"
+	anthropicOutputTool = "submit_output"
+)
+
+// AnthropicRefiner calls the Anthropic Messages API
+type AnthropicRefiner struct {
+	cfg ProviderConfig
+}
+
+func (a *AnthropicRefiner) Name() string           { return ProviderAnthropic }
+func (a *AnthropicRefiner) Model() string          { return a.cfg.Model }
+func (a *AnthropicRefiner) config() ProviderConfig { return a.cfg }
+
+// Complete sends the prompt as a single user message and joins the text blocks of the reply.
+// When the prompt carries a schema, the model is made to answer through a tool
+// whose input schema it is, and the tool input is returned as the reply.
+func (a *AnthropicRefiner) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	payload := map[string]interface{}{
+		"model":       a.cfg.Model,
+		"max_tokens":  a.cfg.MaxTokens,
+		"temperature": a.cfg.Temperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt.Text},
+		},
+	}
+	if a.cfg.StructuredOutput && prompt.Schema != nil {
+		payload["tools"] = []map[string]interface{}{{
+			"name":         anthropicOutputTool,
+			"description":  "Submit the response in the required format",
+			"input_schema": prompt.Schema,
+		}}
+		payload["tool_choice"] = map[string]string{"type": "tool", "name": anthropicOutputTool}
+	}
+
+	headers := map[string]string{
+		"x-api-key":         a.cfg.APIKey,
+		"anthropic-version": AnthropicAPIVersion,
+	}
+
+	url := strings.TrimSuffix(a.cfg.BaseURL, "/") + "/messages"
+	log.Printf("[API-3] Sending request to Anthropic (%s)...", a.cfg.Model)
+
+	bodyBytes, err := postJSON(ctx, url, headers, payload)
+	if err != nil {
+		return "", fmt.Errorf("anthropic: %w", err)
+	}
+
+	var msgResp struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &msgResp); err != nil {
+		return "", fmt.Errorf("failed to parse Anthropic response: %w", err)
+	}
+
+	var parts []string
+	for _, c := range msgResp.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "tool_use":
+			return string(c.Input), nil
+		}
+	}
+	if len(parts) == 0 {
+		return "", errors.New("no text content in Anthropic response")
+	}
+
+	return strings.Join(parts, ""), nil
+}
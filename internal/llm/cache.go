@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"godemo/internal/cache"
+)
+
+// configured is implemented by providers built from a ProviderConfig
+type configured interface {
+	config() ProviderConfig
+}
+
+// scriptCacheKey identifies a script request by provider, sampling settings and
+// prompt. Refiners without a config, like the fake, are not cached.
+func scriptCacheKey(refiner ScriptRefiner, prompt string) string {
+	c, ok := refiner.(configured)
+	if !ok {
+		return ""
+	}
+	cfg := c.config()
+	return cache.Key(cache.NamespaceLLM,
+		TaskScript,
+		cfg.Provider,
+		cfg.Model,
+		cfg.BaseURL,
+		strconv.FormatFloat(cfg.Temperature, 'g', -1, 64),
+		strconv.Itoa(cfg.MaxTokens),
+		strconv.FormatBool(cfg.StructuredOutput),
+		prompt,
+	)
+}
+
+// cachedScript returns the segments stored for key, recording the lookup in ctx
+func cachedScript(ctx context.Context, key string) ([]RefinedSegment, bool) {
+	if key == "" {
+		return nil, false
+	}
+	if data, ok := cache.Default().Get(key); ok {
+		var segments []RefinedSegment
+		if err := json.Unmarshal(data, &segments); err == nil {
+			cache.Record(ctx, cache.NamespaceLLM, true)
+			log.Printf("[CACHE] Script hit (%d segments)", len(segments))
+			return segments, true
+		}
+	}
+	cache.Record(ctx, cache.NamespaceLLM, false)
+	return nil, false
+}
+
+func storeScript(key string, segments []RefinedSegment) {
+	if key == "" {
+		return
+	}
+	if data, err := json.Marshal(segments); err == nil {
+		cache.Default().Put(key, data)
+	}
+}
@@ -0,0 +1,269 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"godemo/internal/models"
+)
+
+const (
+	DefaultSpanSec        = 300.0 // recordings up to 1.25x this are refined in one request
+	DefaultSpanContextSec = 15.0  // how far back the previous span's last sentence may start
+	DefaultSpanOverlapSec = 10.0  // how much of the previous span each span also sees
+	DefaultConcurrency    = 3
+	StitchGapSec          = 1.0 // gaps this short at a span boundary are closed
+)
+
+// ChunkConfig controls how long recordings are split for refinement
+type ChunkConfig struct {
+	SpanSec     float64
+	ContextSec  float64
+	OverlapSec  float64
+	Concurrency int
+}
+
+// DefaultChunkConfig returns the built-in chunking limits
+func DefaultChunkConfig() ChunkConfig {
+	return ChunkConfig{
+		SpanSec:     DefaultSpanSec,
+		ContextSec:  DefaultSpanContextSec,
+		OverlapSec:  DefaultSpanOverlapSec,
+		Concurrency: DefaultConcurrency,
+	}
+}
+
+// ChunkConfigFromEnv overrides the defaults with LLM_SPAN_SEC, LLM_SPAN_CONTEXT_SEC,
+// LLM_SPAN_OVERLAP_SEC and LLM_CONCURRENCY
+func ChunkConfigFromEnv() ChunkConfig {
+	cfg := DefaultChunkConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_SPAN_SEC"), 64); err == nil && v >= 30 {
+		cfg.SpanSec = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_SPAN_CONTEXT_SEC"), 64); err == nil && v >= 0 {
+		cfg.ContextSec = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_SPAN_OVERLAP_SEC"), 64); err == nil && v >= 0 {
+		cfg.OverlapSec = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_CONCURRENCY")); err == nil && v > 0 {
+		cfg.Concurrency = v
+	}
+	return cfg
+}
+
+// span is one slice of the recording refined by its own request. A span sees
+// from Start but narrates from Own; the time between overlaps the previous span.
+type span struct {
+	Start, Own, End float64
+	Previous        string // last transcript sentence before Own, given as context
+}
+
+// partInfo tells the prompt which slice of a longer recording it covers
+type partInfo struct {
+	Index, Count    int
+	Start, Own, End float64 // position in the full recording
+	Previous        string
+}
+
+// planSpans splits the recording into spans of roughly cfg.SpanSec, cutting at
+// the longest pause in the speech near each target boundary. Each span after the
+// first also covers up to cfg.OverlapSec before its cut, starting at a pause, so
+// the model sees what leads into it. Without word timings the transcript cannot
+// be split, so the recording stays whole.
+func planSpans(duration float64, words []models.DeepgramWord, cfg ChunkConfig) []span {
+	if len(words) == 0 || duration <= cfg.SpanSec*1.25 {
+		return []span{{Start: 0, Own: 0, End: duration}}
+	}
+
+	var spans []span
+	start := 0.0
+	for duration-start > cfg.SpanSec*1.25 {
+		cut := pauseNear(words, start+cfg.SpanSec, cfg.SpanSec/4)
+		spans = append(spans, span{Start: start, Own: start, End: cut})
+		start = cut
+	}
+	spans = append(spans, span{Start: start, Own: start, End: duration})
+
+	for i := 1; i < len(spans); i++ {
+		s := &spans[i]
+		if cfg.OverlapSec > 0 {
+			lead := pauseNear(words, s.Own-cfg.OverlapSec, cfg.OverlapSec/2)
+			s.Start = math.Min(math.Max(lead, spans[i-1].Own), s.Own)
+		}
+		s.Previous = lastSentence(words, s.Own, cfg.ContextSec)
+	}
+	return spans
+}
+
+// pauseNear finds the best place to cut within slack of target: the longest
+// silence between words, preferring sentence ends and pauses close to target
+func pauseNear(words []models.DeepgramWord, target, slack float64) float64 {
+	best, bestScore := target, -1.0
+	for i := 0; i+1 < len(words); i++ {
+		gapStart, gapEnd := words[i].End, words[i+1].Start
+		mid := (gapStart + gapEnd) / 2
+		if math.Abs(mid-target) > slack {
+			continue
+		}
+		score := gapEnd - gapStart
+		if endsSentence(wordText(words[i])) {
+			score += 0.5
+		}
+		score *= 1 - 0.5*math.Abs(mid-target)/slack
+		if score > bestScore {
+			best, bestScore = mid, score
+		}
+	}
+	return best
+}
+
+// lastSentence returns the transcript sentence that ends at cut, limited to words
+// starting within maxBack seconds of it
+func lastSentence(words []models.DeepgramWord, cut, maxBack float64) string {
+	var picked []string
+	for i := len(words) - 1; i >= 0; i-- {
+		w := words[i]
+		if w.Start >= cut {
+			continue
+		}
+		if cut-w.Start > maxBack || (len(picked) > 0 && endsSentence(wordText(w))) {
+			break
+		}
+		picked = append([]string{wordText(w)}, picked...)
+	}
+	return strings.Join(picked, " ")
+}
+
+func wordText(w models.DeepgramWord) string {
+	if w.PunctuatedWord != "" {
+		return w.PunctuatedWord
+	}
+	return w.Word
+}
+
+func endsSentence(word string) bool {
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+}
+
+// spanRequest narrows a request to one span, with times shifted to start at 0
+func spanRequest(req RefineScriptRequest, s span, index, count int) RefineScriptRequest {
+	sub := req
+	sub.VideoDuration = s.End - s.Start
+	sub.part = &partInfo{Index: index + 1, Count: count, Start: s.Start, Own: s.Own, End: s.End, Previous: s.Previous}
+
+	sub.DeepgramWords = nil
+	var text []string
+	for _, w := range req.DeepgramWords {
+		if w.Start >= s.Start && w.Start < s.End {
+			w.Start -= s.Start
+			w.End -= s.Start
+			sub.DeepgramWords = append(sub.DeepgramWords, w)
+			text = append(text, wordText(w))
+		}
+	}
+	sub.RawTranscript = strings.Join(text, " ")
+
+	sub.Actions = nil
+	for _, a := range req.Actions {
+		if a.T >= s.Start && a.T < s.End {
+			a.T -= s.Start
+			sub.Actions = append(sub.Actions, a)
+		}
+	}
+	return sub
+}
+
+// refineSpans refines every span in parallel and stitches the results into one script
+func refineSpans(ctx context.Context, refiner ScriptRefiner, req RefineScriptRequest, spans []span, concurrency int) ([]RefinedSegment, error) {
+	log.Printf("[CHUNK] Refining %.0fs recording in %d spans (concurrency %d)", req.VideoDuration, len(spans), concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]RefinedSegment, len(spans))
+	errs := make([]error, len(spans))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, s := range spans {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			segments, err := refineOnce(ctx, refiner, spanRequest(req, s, i, len(spans)))
+			if err != nil {
+				errs[i] = fmt.Errorf("span %d (%.0f-%.0fs): %w", i+1, s.Start, s.End, err)
+				cancel()
+				return
+			}
+			for j := range segments {
+				segments[j].Start += s.Start
+				segments[j].End += s.Start
+			}
+			results[i] = segments
+			log.Printf("[CHUNK] Span %d/%d (%.0f-%.0fs, overlap %.0fs): %d segments", i+1, len(spans), s.Own, s.End, s.Own-s.Start, len(segments))
+		}()
+	}
+	wg.Wait()
+
+	// Report the failure that caused the cancellation rather than its echoes
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stitchSpans(results, spans, req.VideoDuration), nil
+}
+
+// stitchSpans joins per-span scripts. Each span's segments were validated against
+// its own bounds. Segments lying mostly in the overlap belong to the previous
+// span and are dropped. At the seams, short gaps are closed, overlaps trimmed,
+// and a span that opens by repeating the previous line loses that line.
+func stitchSpans(results [][]RefinedSegment, spans []span, videoDuration float64) []RefinedSegment {
+	var out []RefinedSegment
+	for i, segments := range results {
+		if i > 0 {
+			var owned []RefinedSegment
+			for _, seg := range segments {
+				if (seg.Start+seg.End)/2 >= spans[i].Own {
+					owned = append(owned, seg)
+				}
+			}
+			segments = owned
+		}
+		if n := len(out); n > 0 && len(segments) > 0 {
+			prev, next := &out[n-1], &segments[0]
+			if strings.EqualFold(strings.TrimSpace(prev.Text), strings.TrimSpace(next.Text)) {
+				prev.End = math.Max(prev.End, next.End)
+				segments = segments[1:]
+			} else if gap := next.Start - prev.End; gap > 0 && gap <= StitchGapSec {
+				prev.End = next.Start
+			} else if gap < 0 {
+				next.Start = math.Min(prev.End, next.End)
+			}
+		}
+		out = append(out, segments...)
+	}
+	return salvageSegments(out, videoDuration)
+}
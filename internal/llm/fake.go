@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	FakeSegmentSec = 10.0 // length of generated fake segments
+)
+
+// FakeRefiner is an in-process provider for running the pipeline without network access.
+// With no configured Segments it covers the video with back-to-back neutral segments,
+// and shortening requests are answered by truncating the line to its word budget.
+type FakeRefiner struct {
+	Segments []RefinedSegment // returned verbatim when set
+	Response string           // raw text returned when set; takes precedence over Segments
+	Err      error            // returned from every call when set
+
+	mu      sync.Mutex
+	prompts []Prompt
+}
+
+func (f *FakeRefiner) Name() string  { return ProviderFake }
+func (f *FakeRefiner) Model() string { return "fake" }
+
+// Prompts returns every prompt the fake has received
+func (f *FakeRefiner) Prompts() []Prompt {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Prompt(nil), f.prompts...)
+}
+
+// Complete records the prompt and returns the canned response
+func (f *FakeRefiner) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.Response != "" {
+		return f.Response, nil
+	}
+
+	if prompt.Task == TaskShorten {
+		words := strings.Fields(prompt.Source)
+		if len(words) > prompt.MaxWords {
+			words = words[:prompt.MaxWords]
+		}
+		return strings.Join(words, " "), nil
+	}
+
+	segments := f.Segments
+	if segments == nil {
+		segments = fakeSegments(prompt.VideoDuration)
+	}
+
+	out, err := json.Marshal(segments)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// fakeSegments splits the video into fixed-length narration segments
+func fakeSegments(videoDuration float64) []RefinedSegment {
+	var segments []RefinedSegment
+	for start := 0.0; start < videoDuration; start += FakeSegmentSec {
+		end := math.Min(start+FakeSegmentSec, videoDuration)
+		segments = append(segments, RefinedSegment{
+			Start:      start,
+			End:        end,
+			Text:       "Here we continue through the next part of the walkthrough.",
+			MusicStyle: "minimal",
+		})
+	}
+	return segments
+}
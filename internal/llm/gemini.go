@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	GeminiAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+)
+
+// GeminiRefiner calls the Google Gemini generateContent API
+type GeminiRefiner struct {
+	cfg ProviderConfig
+}
+
+func (g *GeminiRefiner) Name() string           { return ProviderGemini }
+func (g *GeminiRefiner) Model() string          { return g.cfg.Model }
+func (g *GeminiRefiner) config() ProviderConfig { return g.cfg }
+
+// Complete sends the prompt to Gemini, passing the key in a header rather than the URL
+func (g *GeminiRefiner) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	log.Println("[API-1] Building Gemini request payload")
+
+	genConfig := map[string]interface{}{
+		"temperature":     g.cfg.Temperature,
+		"maxOutputTokens": g.cfg.MaxTokens,
+	}
+	if g.cfg.StructuredOutput && prompt.Schema != nil {
+		genConfig["responseMimeType"] = "application/json"
+		genConfig["responseSchema"] = geminiSchema(prompt.Schema)
+	}
+
+	payload := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"parts": []map[string]string{
+					{"text": prompt.Text},
+				},
+			},
+		},
+		"generationConfig": genConfig,
+	}
+
+	url := fmt.Sprintf("%s/models/%s:generateContent", strings.TrimSuffix(g.cfg.BaseURL, "/"), g.cfg.Model)
+	log.Printf("[API-3] Sending request to Gemini (%s)...", g.cfg.Model)
+
+	bodyBytes, err := postJSON(ctx, url, map[string]string{"x-goog-api-key": g.cfg.APIKey}, payload)
+	if err != nil {
+		return "", fmt.Errorf("gemini: %w", err)
+	}
+
+	log.Printf("[API-5] Response body size: %d bytes", len(bodyBytes))
+
+	var geminiResp struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &geminiResp); err != nil {
+		return "", fmt.Errorf("failed to parse Gemini response structure: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
+		return "", errors.New("no response from gemini")
+	}
+
+	responseText := geminiResp.Candidates[0].Content.Parts[0].Text
+	log.Printf("[API-6] Extracted response text, length: %d chars", len(responseText))
+
+	return responseText, nil
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// OpenAIRefiner calls an OpenAI-compatible chat completions endpoint.
+// Ollama and llama.cpp servers expose the same API under their own base URL.
+type OpenAIRefiner struct {
+	cfg ProviderConfig
+}
+
+func (o *OpenAIRefiner) Name() string           { return ProviderOpenAI }
+func (o *OpenAIRefiner) Model() string          { return o.cfg.Model }
+func (o *OpenAIRefiner) config() ProviderConfig { return o.cfg }
+
+// Complete sends the prompt as a single user message
+func (o *OpenAIRefiner) Complete(ctx context.Context, prompt Prompt) (string, error) {
+	payload := map[string]interface{}{
+		"model": o.cfg.Model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt.Text},
+		},
+		"temperature": o.cfg.Temperature,
+		"max_tokens":  o.cfg.MaxTokens,
+	}
+	if o.cfg.StructuredOutput && prompt.Schema != nil {
+		payload["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   prompt.Task,
+				"schema": prompt.Schema,
+				"strict": true,
+			},
+		}
+	}
+
+	headers := map[string]string{}
+	if o.cfg.APIKey != "" {
+		headers["Authorization"] = "Bearer " + o.cfg.APIKey
+	}
+
+	url := strings.TrimSuffix(o.cfg.BaseURL, "/") + "/chat/completions"
+	log.Printf("[API-3] Sending request to %s (%s)...", url, o.cfg.Model)
+
+	bodyBytes, err := postJSON(ctx, url, headers, payload)
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+
+	var chatResp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+
+	if err := json.Unmarshal(bodyBytes, &chatResp); err != nil {
+		return "", fmt.Errorf("failed to parse chat completion response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", errors.New("no choices in chat completion response")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,200 @@
+package llm
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	DefaultPreset   = "ecommerce-hype"
+	DefaultLanguage = "English"
+	presetExt       = ".tmpl"
+)
+
+var (
+	ErrPresetNotFound = errors.New("prompt preset not found")
+	ErrPresetBuiltin  = errors.New("built-in presets cannot be deleted")
+)
+
+//go:embed presets/*.tmpl
+var builtinPresets embed.FS
+
+// presetName keeps preset names safe to use as file names
+var presetName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// descriptionComment is the optional {{/* ... */}} first line of a template
+var descriptionComment = regexp.MustCompile(`^\{\{/\*\s*(.*?)\s*\*/\}\}\n?`)
+
+// PromptVars are the per-request values a preset template can use
+type PromptVars struct {
+	Topic    string `json:"topic,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	Product  string `json:"productName,omitempty"`
+	Language string `json:"language,omitempty"`
+}
+
+// promptData is everything available to a template as {{.Field}}
+type promptData struct {
+	PromptVars
+	Duration       float64
+	Transcript     string
+	Actions        string
+	WordsPerSecond float64
+}
+
+// Preset is a named narration prompt template
+type Preset struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Template    string `json:"template"`
+	Builtin     bool   `json:"builtin"`
+}
+
+// PresetDir returns where runtime presets are stored (PROMPT_PRESETS_DIR, default "presets").
+// Files there override built-ins of the same name and are read on every request,
+// so presets can be added or edited without a restart.
+func PresetDir() string {
+	if dir := os.Getenv("PROMPT_PRESETS_DIR"); dir != "" {
+		return dir
+	}
+	return "presets"
+}
+
+// DefaultPresetName is the preset used when a request names none (LLM_PROMPT_PRESET)
+func DefaultPresetName() string {
+	if name := os.Getenv("LLM_PROMPT_PRESET"); name != "" {
+		return name
+	}
+	return DefaultPreset
+}
+
+// GetPreset looks up a preset by name, preferring runtime files over built-ins
+func GetPreset(name string) (*Preset, error) {
+	if name == "" {
+		name = DefaultPresetName()
+	}
+	if !presetName.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrPresetNotFound, name)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(PresetDir(), name+presetExt)); err == nil {
+		return newPreset(name, string(data), false), nil
+	}
+	if data, err := builtinPresets.ReadFile("presets/" + name + presetExt); err == nil {
+		return newPreset(name, string(data), true), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrPresetNotFound, name)
+}
+
+// ListPresets returns every available preset, sorted by name
+func ListPresets() []Preset {
+	names := map[string]bool{}
+
+	entries, _ := builtinPresets.ReadDir("presets")
+	for _, e := range entries {
+		names[strings.TrimSuffix(e.Name(), presetExt)] = true
+	}
+	files, _ := filepath.Glob(filepath.Join(PresetDir(), "*"+presetExt))
+	for _, f := range files {
+		names[strings.TrimSuffix(filepath.Base(f), presetExt)] = true
+	}
+
+	var out []Preset
+	for name := range names {
+		if p, err := GetPreset(name); err == nil {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// SavePreset validates and stores a runtime preset, replacing any existing one
+func SavePreset(name, description, body string) (*Preset, error) {
+	if !presetName.MatchString(name) {
+		return nil, fmt.Errorf("invalid preset name %q (lowercase letters, digits, - and _)", name)
+	}
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("template is empty")
+	}
+	if !strings.Contains(body, ".Transcript") {
+		return nil, errors.New("template must include {{.Transcript}}")
+	}
+
+	text := body
+	if description = strings.TrimSpace(strings.ReplaceAll(description, "*/", "")); description != "" {
+		text = fmt.Sprintf("{{/* %s */}}\n%s", description, body)
+	}
+
+	// Render once with sample data so broken templates fail here, not mid-pipeline
+	p := newPreset(name, text, false)
+	if _, err := p.render(promptData{Duration: 30, Transcript: "sample", Actions: "sample"}); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(PresetDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+	path := filepath.Join(PresetDir(), name+presetExt)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write preset: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, fmt.Errorf("failed to write preset: %v", err)
+	}
+	return p, nil
+}
+
+// DeletePreset removes a runtime preset. Deleting an override restores the built-in.
+func DeletePreset(name string) error {
+	if !presetName.MatchString(name) {
+		return fmt.Errorf("%w: %q", ErrPresetNotFound, name)
+	}
+	err := os.Remove(filepath.Join(PresetDir(), name+presetExt))
+	if errors.Is(err, os.ErrNotExist) {
+		if _, berr := builtinPresets.ReadFile("presets/" + name + presetExt); berr == nil {
+			return ErrPresetBuiltin
+		}
+		return fmt.Errorf("%w: %q", ErrPresetNotFound, name)
+	}
+	return err
+}
+
+func newPreset(name, text string, builtin bool) *Preset {
+	p := &Preset{Name: name, Template: text, Builtin: builtin}
+	if m := descriptionComment.FindStringSubmatch(text); m != nil {
+		p.Description = m[1]
+		p.Template = text[len(m[0]):]
+	}
+	return p
+}
+
+// render executes the preset template
+func (p *Preset) render(data promptData) (string, error) {
+	tmpl, err := template.New(p.Name).Option("missingkey=error").Parse(p.Template)
+	if err != nil {
+		return "", fmt.Errorf("preset %s: %v", p.Name, err)
+	}
+
+	if data.Language == "" {
+		data.Language = DefaultLanguage
+	}
+	if data.WordsPerSecond == 0 {
+		data.WordsPerSecond = TargetWordsPerSecond
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("preset %s: %v", p.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Supported providers
+const (
+	ProviderGemini    = "gemini"
+	ProviderOpenAI    = "openai" // any OpenAI-compatible server, including Ollama and llama.cpp
+	ProviderAnthropic = "anthropic"
+	ProviderFake      = "fake"
+)
+
+// Prompt tasks
+const (
+	TaskScript  = "script"  // full narration script as JSON segments
+	TaskShorten = "shorten" // single line rewritten to fewer words
+)
+
+// Prompt is a single request sent to a provider
+type Prompt struct {
+	Task          string
+	Text          string
+	VideoDuration float64 // TaskScript: length of the recording
+	Source        string  // TaskShorten: the line being shortened
+	MaxWords      int     // TaskShorten: word budget
+
+	// Schema is the JSON schema the reply must match. Providers that support
+	// structured output enforce it; the others only see the prompt's instructions.
+	Schema map[string]interface{}
+}
+
+// ScriptRefiner is an LLM backend that completes a narration prompt into raw model text
+type ScriptRefiner interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, prompt Prompt) (string, error)
+}
+
+// ProviderConfig selects and tunes the LLM backend
+type ProviderConfig struct {
+	Provider    string
+	Model       string
+	APIKey      string
+	BaseURL     string
+	Temperature float64
+	MaxTokens   int
+
+	StructuredOutput bool // send Prompt.Schema to providers that can enforce it
+}
+
+// ConfigFromEnv reads the LLM configuration from the environment.
+//
+//	LLM_PROVIDER     gemini (default) | openai | anthropic | fake
+//	LLM_MODEL        model name, provider default when empty
+//	LLM_API_KEY      falls back to GEMINI_API_KEY / OPENAI_API_KEY / ANTHROPIC_API_KEY
+//	LLM_BASE_URL     API root, e.g. http://localhost:11434/v1 for Ollama
+//	LLM_TEMPERATURE  sampling temperature
+//	LLM_MAX_TOKENS   output token limit
+//	LLM_STRUCTURED_OUTPUT  "false" for OpenAI-compatible servers without json_schema support
+func ConfigFromEnv() ProviderConfig {
+	cfg := ProviderConfig{
+		Provider: strings.ToLower(os.Getenv("LLM_PROVIDER")),
+		Model:    os.Getenv("LLM_MODEL"),
+		APIKey:   os.Getenv("LLM_API_KEY"),
+		BaseURL:  os.Getenv("LLM_BASE_URL"),
+
+		StructuredOutput: true,
+	}
+	if v, err := strconv.ParseBool(os.Getenv("LLM_STRUCTURED_OUTPUT")); err == nil {
+		cfg.StructuredOutput = v
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = ProviderGemini
+	}
+	if cfg.APIKey == "" {
+		switch cfg.Provider {
+		case ProviderGemini:
+			cfg.APIKey = os.Getenv("GEMINI_API_KEY")
+		case ProviderOpenAI:
+			cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		case ProviderAnthropic:
+			cfg.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+		}
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("LLM_TEMPERATURE"), 64); err == nil {
+		cfg.Temperature = v
+	} else {
+		cfg.Temperature = -1 // provider default
+	}
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_TOKENS")); err == nil && v > 0 {
+		cfg.MaxTokens = v
+	}
+	return cfg
+}
+
+// NewRefiner builds the ScriptRefiner named by cfg.Provider, filling in provider defaults
+func NewRefiner(cfg ProviderConfig) (ScriptRefiner, error) {
+	if cfg.Temperature < 0 {
+		cfg.Temperature = 0.3
+	}
+	if cfg.MaxTokens <= 0 {
+		cfg.MaxTokens = 4096
+	}
+
+	switch cfg.Provider {
+	case ProviderGemini, "":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY environment variable is required")
+		}
+		if cfg.Model == "" {
+			cfg.Model = "gemini-2.5-flash-lite"
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = GeminiAPIBaseURL
+		}
+		return &GeminiRefiner{cfg: cfg}, nil
+
+	case ProviderOpenAI:
+		// Local servers (Ollama, llama.cpp) accept requests without a key
+		if cfg.BaseURL == "" {
+			if cfg.APIKey == "" {
+				return nil, fmt.Errorf("OPENAI_API_KEY or LLM_BASE_URL is required for the openai provider")
+			}
+			cfg.BaseURL = "https://api.openai.com/v1"
+		}
+		if cfg.Model == "" {
+			cfg.Model = "gpt-4o-mini"
+		}
+		return &OpenAIRefiner{cfg: cfg}, nil
+
+	case ProviderAnthropic:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
+		}
+		if cfg.Model == "" {
+			cfg.Model = "claude-3-5-haiku-latest"
+		}
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = "https://api.anthropic.com/v1"
+		}
+		return &AnthropicRefiner{cfg: cfg}, nil
+
+	case ProviderFake:
+		return &FakeRefiner{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", cfg.Provider)
+	}
+}
+
+// postJSON sends payload to url and returns the body of a 200 response
+func postJSON(ctx context.Context, url string, headers map[string]string, payload interface{}) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
@@ -1,31 +1,30 @@
 package llm
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
 	"strings"
 
 	"godemo/internal/models"
 )
 
-const (
-	GeminiAPIURL = "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-flash-lite:generateContent"
-)
-
 // RefineScriptRequest contains all inputs for LLM script refinement
 type RefineScriptRequest struct {
 	RawTranscript string
-	DOMEvents     []models.DomEvent
+	Actions       []models.TimelineItem // normalized actions with page state
 	VideoDuration float64
 	DeepgramWords []models.DeepgramWord
+	Preset        string     // prompt preset name; empty uses DefaultPresetName()
+	Vars          PromptVars // topic, audience, product and language for the preset
+
+	part *partInfo // set when the request covers one span of a longer recording
 }
 
+// TargetWordsPerSecond is the speaking rate scripts are written for
+const TargetWordsPerSecond = 2.5
+
 // RefinedSegment represents a clean narration segment with timing
 type RefinedSegment struct {
 	Start      float64 `json:"start"`
@@ -34,227 +33,238 @@ type RefinedSegment struct {
 	MusicStyle string  `json:"musicStyle"` // Suggestion for background music (e.g. tech, upbeat, travel)
 }
 
-// RefineScript uses Gemini to clean up the transcript and generate professional narration
-func RefineScript(req RefineScriptRequest, apiKey string) ([]RefinedSegment, error) {
+// RefineScript uses the configured LLM provider to clean up the transcript and generate professional narration
+func RefineScript(ctx context.Context, refiner ScriptRefiner, req RefineScriptRequest) ([]RefinedSegment, error) {
 	log.Println("[STEP 1] RefineScript called")
 	
-	if apiKey == "" {
-		return nil, errors.New("gemini API key required")
+	if refiner == nil {
+		return nil, errors.New("no LLM provider configured")
+	}
+	log.Printf("[STEP 2] Using provider %s (%s)", refiner.Name(), refiner.Model())
+
+	// Long recordings would overflow the output token limit in a single request
+	cfg := ChunkConfigFromEnv()
+	if spans := planSpans(req.VideoDuration, req.DeepgramWords, cfg); len(spans) > 1 {
+		return refineSpans(ctx, refiner, req, spans, cfg.Concurrency)
 	}
-	log.Printf("[STEP 2] API key present: %d chars", len(apiKey))
+	return refineOnce(ctx, refiner, req)
+}
 
+// refineOnce sends one script request, answering from the cache when the same prompt was refined before
+func refineOnce(ctx context.Context, refiner ScriptRefiner, req RefineScriptRequest) ([]RefinedSegment, error) {
 	// Build the prompt
-	prompt := buildPrompt(req)
+	prompt, err := buildPrompt(req)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("[STEP 3] Built prompt, length: %d chars", len(prompt))
 
-	// Call Gemini API
-	segments, err := callGeminiAPI(prompt, apiKey)
-	if err != nil {
-		log.Printf("[ERROR] Gemini API call failed: %v", err)
-		return nil, fmt.Errorf("gemini API error: %w", err)
+	key := scriptCacheKey(refiner, prompt)
+	if segments, ok := cachedScript(ctx, key); ok {
+		return segments, nil
 	}
 
-	log.Printf("[STEP 4] Successfully got %d segments from Gemini", len(segments))
+	segments, valid, err := completeScript(ctx, refiner, req, prompt)
+	if err != nil {
+		return nil, err
+	}
+	// Salvaged scripts are only good enough for this run; a rerun should ask again
+	if valid {
+		storeScript(key, segments)
+	}
 	return segments, nil
 }
 
-func buildPrompt(req RefineScriptRequest) string {
-	// Extract action summary
-	actionSummary := summarizeActions(req.DOMEvents)
-
-	prompt := fmt.Sprintf(`You are a professional video narrator. Your task is to create a clean, natural narration script for a screen recording.
-
-VIDEO DETAILS:
-- Duration: %.2f seconds
-- Topic: E-commerce website demonstration
-
-RAW TRANSCRIPT (messy):
-%s
-
-USER ACTIONS:
-%s
-
-TASK:
-1. Clean up the transcript and create a professional, HYPER-ENTHUSIASTIC, ENCOURAGING, and BOLD script.
-   
-2. IMPORTANT - BE INFECTIOUSLY EXCITED:
-   - Use high-energy, persuasive language. Use words like "Revolutionary!", "Incredible!", "Absolute game-changer!", "You're going to love this!".
-   - Make the user feel ENCOURAGED to shop and explore. 
-   - Every sentence should sound like it's a huge benefit for the viewer.
-
-3. FILL THE SILENCE:
-   - The video is %.2f seconds long. You MUST provide enough words to fill the entire duration.
-   - For every segment, aim for ~2.5 words per second of duration. 
-   - DESCRIBE the interface with passion if you run out of transcript text.
+// completeScript calls the provider until it returns a valid script or the repair budget runs out.
+// The flag is false when the segments were salvaged from invalid responses.
+func completeScript(ctx context.Context, refiner ScriptRefiner, req RefineScriptRequest, prompt string) ([]RefinedSegment, bool, error) {
+	// Call the provider, asking for schema-constrained output, and repair invalid responses
+	p := Prompt{Task: TaskScript, Text: prompt, VideoDuration: req.VideoDuration, Schema: segmentSchema()}
+	maxRepairs := MaxRepairsFromEnv()
+	var best []RefinedSegment
+
+	for attempt := 0; ; attempt++ {
+		responseText, err := refiner.Complete(ctx, p)
+		if err != nil {
+			log.Printf("[ERROR] %s API call failed: %v", refiner.Name(), err)
+			if len(best) > 0 && ctx.Err() == nil {
+				log.Printf("[WARN] Using %d salvaged segments after failed repair call", len(best))
+				return best, false, nil
+			}
+			return nil, false, fmt.Errorf("%s API error: %w", refiner.Name(), err)
+		}
 
-4. EXPRESSIVE PUNCTUATION:
-   - Aggressively use exclamation marks (!) and rhetorical questions (?) to keep the energy peaking!
+		segments, err := decodeSegments(responseText)
+		issues := validateSegments(segments, req.VideoDuration)
+		if err != nil {
+			issues = append([]string{err.Error()}, issues...)
+		}
+		if len(issues) == 0 {
+			log.Printf("[STEP 4] Successfully got %d segments from %s", len(segments), refiner.Name())
+			return segments, true, nil
+		}
 
-5. STRETCH NARRATION:
-   - Back-to-back segments (no gaps). 0-10, 10-20, etc.
+		if salvaged := salvageSegments(segments, req.VideoDuration); len(salvaged) > len(best) {
+			best = salvaged
+		}
+		log.Printf("[WARN] Script response attempt %d has %d problems (first: %s)", attempt+1, len(issues), issues[0])
 
-6. BACKGROUND MUSIC THEMES:
-   - Suggest "upbeat", "tech", "luxury", "travel", or "minimal".
+		if attempt >= maxRepairs {
+			if len(best) == 0 {
+				return nil, false, fmt.Errorf("%s returned no usable segments: %s", refiner.Name(), strings.Join(issues, "; "))
+			}
+			log.Printf("[WARN] Using %d salvaged segments after %d repair attempts", len(best), maxRepairs)
+			return best, false, nil
+		}
+		p.Text = repairPrompt(prompt, responseText, issues)
+	}
+}
 
-7. Return JSON array format:
-[
-  {"start": 0, "end": 10.0, "text": "Welcome to Flipkart! The absolute BEST place for all your shopping needs! You've got to see these incredible brands and deals we have for you today! Let's jump in!", "musicStyle": "upbeat"}
-]
+// outputFormat is appended to every preset so all of them return parseable segments
+const outputFormat = `OUTPUT FORMAT:
+- Back-to-back segments covering the whole video with no gaps (e.g. 0-10, 10-20, ...).
+- Times in seconds, increasing, and never past the video duration.
+- Give every segment a "musicStyle": "upbeat", "tech", "luxury", "travel" or "minimal".
+- Return ONLY a valid JSON object:
+{"segments": [
+  {"start": 0, "end": 10.0, "text": "...", "musicStyle": "minimal"}
+]}
+
+OUTPUT (JSON only):`
+
+// buildPrompt renders the request's preset with the transcript and action summary
+func buildPrompt(req RefineScriptRequest) (string, error) {
+	preset, err := GetPreset(req.Preset)
+	if err != nil {
+		return "", err
+	}
 
-IMPORTANT RULES:
-- Use VIBRANT, BOLD, and INFECTIOUSLY ENTHUSIASTIC language.
-- Provide a "musicStyle" for EVERY segment.
-- FILL THE TIME with high-energy hype.
-- Return ONLY valid JSON.
+	body, err := preset.render(promptData{
+		PromptVars: req.Vars,
+		Duration:   req.VideoDuration,
+		Transcript: req.RawTranscript,
+		Actions:    summarizeActions(req.Actions),
+	})
+	if err != nil {
+		return "", err
+	}
 
-OUTPUT (JSON only):`, req.VideoDuration, req.RawTranscript, actionSummary, req.VideoDuration, req.VideoDuration)
+	if p := req.part; p != nil {
+		body += "\n\n" + partNote(p)
+	}
+	return body + "\n\n" + outputFormat, nil
+}
 
-	return prompt
+// partNote explains to the model that it is writing one part of a longer script
+func partNote(p *partInfo) string {
+	note := fmt.Sprintf(`PART %d OF %d:
+This is one part of a longer recording, covering %.1fs to %.1fs of the full video.
+All times above and in your output are relative to the start of this part.`, p.Index, p.Count, p.Start, p.End)
+	if p.Index > 1 {
+		note += "\nDo not introduce the video again; continue the narration from the previous part."
+	}
+	if lead := p.Own - p.Start; lead > 0 {
+		note += fmt.Sprintf("\nThe first %.1fs overlap the previous part, which already narrates them. Use them for context only and start your narration at %.1fs.", lead, lead)
+	}
+	if p.Previous != "" {
+		note += fmt.Sprintf("\nThe previous part ends with the speaker saying: %q\nContinue naturally from it without repeating it.", p.Previous)
+	}
+	return note
 }
 
-func summarizeActions(events []models.DomEvent) string {
-	if len(events) == 0 {
+func summarizeActions(actions []models.TimelineItem) string {
+	if len(actions) == 0 {
 		return "No specific actions recorded"
 	}
 
-	var actions []string
+	var lines []string
 	clickCount := 0
-	scrollCount := 0
+	page := ""
+
+	for _, a := range actions {
+		// Note page changes so the script can name where things happen
+		if a.URL != "" && a.URL != page {
+			if a.Action != "navigation" {
+				lines = append(lines, fmt.Sprintf("- %.1fs: On page %s", a.T, a.URL))
+			}
+			page = a.URL
+		}
 
-	for _, e := range events {
-		switch e.Type {
+		switch a.Action {
 		case "click":
 			clickCount++
 			if clickCount <= 3 {
-				selector := "element"
-				if target, ok := e.Target["selector"].(string); ok && target != "" {
-					selector = target
-				}
-				actions = append(actions, fmt.Sprintf("- Clicked: %s", selector))
+				lines = append(lines, fmt.Sprintf("- %.1fs: Clicked: %s", a.T, describeTarget(a)))
 			}
 		case "scroll":
-			scrollCount++
+			if line := describeScroll(a); line != "" {
+				lines = append(lines, fmt.Sprintf("- %.1fs: %s", a.T, line))
+			}
 		case "input":
-			actions = append(actions, "- Entered text into form field")
+			lines = append(lines, fmt.Sprintf("- %.1fs: Entered text into form field %s", a.T, describeTarget(a)))
 		case "navigation":
-			actions = append(actions, "- Navigated to new page")
+			label, _ := a.Target["label"].(string)
+			if label == "" {
+				label = "new page"
+			}
+			lines = append(lines, fmt.Sprintf("- %.1fs: Navigated to %s (%s)", a.T, label, a.URL))
 		}
 	}
 
-	if scrollCount > 0 {
-		actions = append(actions, fmt.Sprintf("- Scrolled through page (%d times)", scrollCount))
+	if len(lines) == 0 {
+		return "No specific actions recorded"
 	}
-
-	return strings.Join(actions, "\n")
+	return strings.Join(lines, "\n")
 }
 
-func callGeminiAPI(prompt string, apiKey string) ([]RefinedSegment, error) {
-	log.Println("[API-1] Building Gemini request payload")
-	
-	// Build request payload with higher maxOutputTokens
-	payload := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]string{
-					{"text": prompt},
-				},
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.3,
-			"maxOutputTokens": 4096, // Increased from 2048
-		},
+// describeTarget names an action's element, preferring short visible text over selectors
+func describeTarget(a models.TimelineItem) string {
+	for _, k := range []string{"ariaLabel", "text", "name"} {
+		if s, ok := a.Target[k].(string); ok && s != "" && len(s) <= 40 {
+			return fmt.Sprintf("%q", s)
+		}
 	}
-
-	jsonData, _ := json.Marshal(payload)
-	log.Printf("[API-2] Request payload size: %d bytes", len(jsonData))
-
-	// Make HTTP request
-	url := fmt.Sprintf("%s?key=%s", GeminiAPIURL, apiKey)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, err
+	if s, ok := a.Target["selector"].(string); ok && s != "" {
+		return s
 	}
+	return "element"
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	log.Println("[API-3] Sending request to Gemini...")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	log.Printf("[API-4] Got response with status: %d", resp.StatusCode)
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("[ERROR] Non-200 response: %s", string(body))
-		return nil, fmt.Errorf("gemini API returned %d: %s", resp.StatusCode, string(body))
+// describeScroll turns a scroll burst into "Scrolled down 2.5 screens (1600px)"
+func describeScroll(a models.TimelineItem) string {
+	if a.Scroll == nil {
+		return "Scrolled the page"
 	}
-
-	// Parse response
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if a.Scroll.Direction == "none" {
+		return ""
 	}
-	
-	log.Printf("[API-5] Response body size: %d bytes", len(bodyBytes))
-	
-	var geminiResp struct {
-		Candidates []struct {
-			Content struct {
-				Parts []struct {
-					Text string `json:"text"`
-				} `json:"parts"`
-			} `json:"content"`
-		} `json:"candidates"`
+	if a.Scroll.Screens > 0 {
+		return fmt.Sprintf("Scrolled %s %.1f screens (%.0fpx, now at %.0fpx from the top)", a.Scroll.Direction, a.Scroll.Screens, a.Scroll.Distance, a.Scroll.To.Y)
 	}
+	return fmt.Sprintf("Scrolled %s %.0fpx", a.Scroll.Direction, a.Scroll.Distance)
+}
 
-	if err := json.Unmarshal(bodyBytes, &geminiResp); err != nil {
-		return nil, fmt.Errorf("failed to parse Gemini response structure: %w", err)
+// ShortenText asks the LLM to rewrite one narration line so it can be spoken within maxSeconds
+func ShortenText(ctx context.Context, refiner ScriptRefiner, text string, maxWords int, maxSeconds float64) (string, error) {
+	if maxWords < 3 {
+		return "", fmt.Errorf("window too short to rewrite (%d words)", maxWords)
 	}
 
-	if len(geminiResp.Candidates) == 0 || len(geminiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, errors.New("no response from gemini")
-	}
+	prompt := fmt.Sprintf(`Rewrite this video narration line so it can be spoken in at most %.1f seconds.
+Use no more than %d words. Keep the meaning, tone and any product names.
+Return ONLY the rewritten line, with no quotes or commentary.
 
-	responseText := geminiResp.Candidates[0].Content.Parts[0].Text
-	
-	log.Printf("[API-6] Extracted response text, length: %d chars", len(responseText))
-	log.Printf("[DEBUG] Full Gemini response:\n%s\n[END OF RESPONSE]", responseText)
-	
-	// Write to file for debugging
-	os.WriteFile("gemini_response.txt", []byte(responseText), 0644)
-	log.Println("[API-6b] Wrote response to gemini_response.txt for inspection")
-
-	// Clean response (remove markdown code blocks if present)
-	responseText = strings.TrimSpace(responseText)
-	responseText = strings.TrimPrefix(responseText, "```json")
-	responseText = strings.TrimPrefix(responseText, "```")
-	responseText = strings.TrimSuffix(responseText, "```")
-	responseText = strings.TrimSpace(responseText)
-	
-	log.Printf("[API-7] Cleaned response text, length: %d chars", len(responseText))
-
-	// Parse JSON response
-	var segments []RefinedSegment
-	if err := json.Unmarshal([]byte(responseText), &segments); err != nil {
-		log.Printf("[ERROR] JSON parsing failed: %v", err)
-		log.Printf("[ERROR] Attempted to parse: %s", responseText)
-		return nil, fmt.Errorf("failed to parse gemini response as JSON: %w", err)
-	}
+LINE:
+%s`, maxSeconds, maxWords, text)
 
-	// Validate segments
-	if len(segments) == 0 {
-		return nil, errors.New("gemini returned empty segments array")
-	}
-	
-	log.Printf("[SUCCESS] Successfully parsed %d narration segments from Gemini", len(segments))
-	for i, seg := range segments {
-		log.Printf("  Segment %d: %.1f-%.1fs, %d chars", i+1, seg.Start, seg.End, len(seg.Text))
+	out, err := refiner.Complete(ctx, Prompt{Task: TaskShorten, Text: prompt, Source: text, MaxWords: maxWords})
+	if err != nil {
+		return "", fmt.Errorf("%s API error: %w", refiner.Name(), err)
 	}
 
-	return segments, nil
+	out = strings.Trim(strings.TrimSpace(out), `"`)
+	if out == "" {
+		return "", errors.New("LLM returned an empty rewrite")
+	}
+	return out, nil
 }
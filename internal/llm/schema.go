@@ -0,0 +1,222 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultMaxRepairs   = 2
+	DefaultMusicStyle   = "minimal"
+	SegmentToleranceSec = 0.5  // overlap or overrun tolerated before a segment is flagged
+	MaxEchoedResponse   = 8000 // characters of a bad response quoted back in a repair prompt
+)
+
+// MusicStyles are the background tracks the mixer knows about
+var MusicStyles = []string{"upbeat", "tech", "luxury", "travel", "minimal"}
+
+// MaxRepairsFromEnv reads LLM_MAX_REPAIRS, the number of repair prompts sent
+// after an invalid script response
+func MaxRepairsFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("LLM_MAX_REPAIRS")); err == nil && v >= 0 {
+		return v
+	}
+	return DefaultMaxRepairs
+}
+
+// segmentSchema is the JSON schema a script response must follow. The root is an
+// object because strict structured output modes do not accept bare arrays.
+func segmentSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"segments": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"start":      map[string]interface{}{"type": "number"},
+						"end":        map[string]interface{}{"type": "number"},
+						"text":       map[string]interface{}{"type": "string"},
+						"musicStyle": map[string]interface{}{"type": "string", "enum": MusicStyles},
+					},
+					"required":             []string{"start", "end", "text", "musicStyle"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"segments"},
+		"additionalProperties": false,
+	}
+}
+
+// geminiSchema converts a JSON schema to the OpenAPI subset Gemini accepts:
+// upper-case type names and no additionalProperties
+func geminiSchema(schema map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range schema {
+		switch k {
+		case "additionalProperties":
+			continue
+		case "type":
+			out[k] = strings.ToUpper(v.(string))
+		case "items":
+			out[k] = geminiSchema(v.(map[string]interface{}))
+		case "properties":
+			props := map[string]interface{}{}
+			for name, p := range v.(map[string]interface{}) {
+				props[name] = geminiSchema(p.(map[string]interface{}))
+			}
+			out[k] = props
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// decodeSegments parses a script response, accepting {"segments": [...]} or a bare
+// array, with or without markdown fences. When the JSON is truncated or malformed
+// it returns the segments decoded before the break along with the error.
+func decodeSegments(text string) ([]RefinedSegment, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = strings.TrimSpace(text)
+
+	var wrapped struct {
+		Segments []RefinedSegment `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(text), &wrapped); err == nil && wrapped.Segments != nil {
+		return wrapped.Segments, nil
+	}
+	var bare []RefinedSegment
+	if err := json.Unmarshal([]byte(text), &bare); err == nil {
+		return bare, nil
+	}
+
+	return salvageJSON(text)
+}
+
+// salvageJSON decodes segment objects one at a time from the first array in
+// text, keeping everything before the point where the JSON breaks
+func salvageJSON(text string) ([]RefinedSegment, error) {
+	start := strings.Index(text, "[")
+	if start < 0 {
+		return nil, errors.New("response contains no JSON array")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(text[start:])))
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var out []RefinedSegment
+	for dec.More() {
+		var seg RefinedSegment
+		if err := dec.Decode(&seg); err != nil {
+			return out, fmt.Errorf("invalid JSON after %d segments (truncated response?): %v", len(out), err)
+		}
+		out = append(out, seg)
+	}
+	if _, err := dec.Token(); err != nil {
+		return out, fmt.Errorf("JSON array not closed after %d segments (truncated response?)", len(out))
+	}
+	return out, nil
+}
+
+// validateSegments lists every problem with a parsed script
+func validateSegments(segments []RefinedSegment, videoDuration float64) []string {
+	var issues []string
+	if len(segments) == 0 {
+		return []string{"no segments returned"}
+	}
+
+	prevEnd := 0.0
+	for i, s := range segments {
+		n := i + 1
+		if strings.TrimSpace(s.Text) == "" {
+			issues = append(issues, fmt.Sprintf("segment %d: text is empty", n))
+		}
+		if s.Start < 0 {
+			issues = append(issues, fmt.Sprintf("segment %d: start %.2f is negative", n, s.Start))
+		}
+		if s.End <= s.Start {
+			issues = append(issues, fmt.Sprintf("segment %d: end %.2f is not after start %.2f", n, s.End, s.Start))
+		}
+		if videoDuration > 0 && s.End > videoDuration+SegmentToleranceSec {
+			issues = append(issues, fmt.Sprintf("segment %d: end %.2f is past the video duration %.2f", n, s.End, videoDuration))
+		}
+		if i > 0 && s.Start < prevEnd-SegmentToleranceSec {
+			issues = append(issues, fmt.Sprintf("segment %d: starts at %.2f, before segment %d ends at %.2f; times must increase", n, s.Start, i, prevEnd))
+		}
+		if !validMusicStyle(s.MusicStyle) {
+			issues = append(issues, fmt.Sprintf("segment %d: musicStyle %q is not one of %s", n, s.MusicStyle, strings.Join(MusicStyles, ", ")))
+		}
+		prevEnd = math.Max(prevEnd, s.End)
+	}
+	return issues
+}
+
+// salvageSegments keeps what can be used from an invalid script: times are
+// clamped to the video and to the previous segment, unknown music styles fall
+// back to the default, and segments that stay broken are dropped
+func salvageSegments(segments []RefinedSegment, videoDuration float64) []RefinedSegment {
+	var out []RefinedSegment
+	prevEnd := 0.0
+
+	for _, s := range segments {
+		s.Text = strings.TrimSpace(s.Text)
+		if s.Text == "" {
+			continue
+		}
+		s.Start = math.Max(s.Start, prevEnd)
+		if videoDuration > 0 {
+			s.End = math.Min(s.End, videoDuration)
+		}
+		if s.End <= s.Start {
+			continue
+		}
+		if !validMusicStyle(s.MusicStyle) {
+			s.MusicStyle = DefaultMusicStyle
+		}
+		out = append(out, s)
+		prevEnd = s.End
+	}
+	return out
+}
+
+func validMusicStyle(style string) bool {
+	for _, m := range MusicStyles {
+		if style == m {
+			return true
+		}
+	}
+	return false
+}
+
+// repairPrompt asks the model to fix its previous response
+func repairPrompt(original, response string, issues []string) string {
+	if len(response) > MaxEchoedResponse {
+		response = response[:MaxEchoedResponse] + "\n...(cut off)"
+	}
+	return fmt.Sprintf(`%s
+
+YOUR PREVIOUS RESPONSE:
+%s
+
+That response had these problems:
+- %s
+
+Return the complete corrected script, fixing every problem listed above.
+If the response was cut off, use fewer, shorter segments so it fits.
+
+OUTPUT (JSON only):`, strings.TrimSuffix(original, "OUTPUT (JSON only):"), response, strings.Join(issues, "\n- "))
+}
@@ -4,13 +4,43 @@ package models
 type EffectTarget struct {
 	Selector string       `json:"selector,omitempty"`
 	Bounds   *BoundingBox `json:"bounds,omitempty"`
+
+	// Bounds as fractions of the viewport, so renderers can map them onto any frame size
+	ViewportBounds *BoundingBox `json:"viewportBounds,omitempty"`
 }
 
 // DisplayEffect represents a visual effect to be applied during playback
 type DisplayEffect struct {
 	Start  float64            `json:"start"`
 	End    float64            `json:"end"`
-	Type   string             `json:"type"` // "highlight" | "zoom" | "focus" | "dim" | "blur" | "label"
+	Type   string             `json:"type"` // "highlight" | "zoom" | "focus" | "dim" | "blur" | "label" | "click"
 	Target *EffectTarget      `json:"target,omitempty"`
 	Style  map[string]interface{} `json:"style,omitempty"`
 }
+
+// CameraKeyframe is one point of the camera path. Between keyframes the camera
+// moves from the previous state to this one using this keyframe's easing.
+type CameraKeyframe struct {
+	T      float64 `json:"t"`
+	X      float64 `json:"x"`                // view center as a fraction of the viewport width
+	Y      float64 `json:"y"`                // view center as a fraction of the viewport height
+	Scale  float64 `json:"scale"`            // 1 shows the whole viewport
+	Easing string  `json:"easing"`           // "linear" | "ease-in-out" | "ease-out"
+	Reason string  `json:"reason,omitempty"` // "overview" | "focus" | "typing" | "navigation" | "scroll"
+}
+
+// CursorPoint is one point of the cursor track
+type CursorPoint struct {
+	T      float64 `json:"t"`
+	X      float64 `json:"x"`                // fraction of the viewport width
+	Y      float64 `json:"y"`                // fraction of the viewport height
+	Easing string  `json:"easing,omitempty"` // how the pointer moves from the previous point
+	Click  bool    `json:"click,omitempty"`
+}
+
+// CursorTrack is the pointer path over the video, either simplified from
+// recorded mousemove samples or synthesized between action targets
+type CursorTrack struct {
+	Source string        `json:"source"` // "recorded" | "synthesized"
+	Points []CursorPoint `json:"points"`
+}
@@ -11,13 +11,78 @@ type Narration struct {
 
 // AudioChunk represents a piece of synthesized audio mapped to a narration window
 type AudioChunk struct {
+	NarrationIndex int          `json:"narrationIndex"` // narration this chunk voices; several may share a window
+	WindowIndex    int          `json:"windowIndex"`
+	Start          float64      `json:"start"`
+	End            float64      `json:"end"`
+	Duration       float64      `json:"duration"`
+	Text           string       `json:"text"`
+	Provider       string       `json:"provider"`
+	MusicStyle     string       `json:"musicStyle,omitempty"`
+	AudioURL       string       `json:"audioUrl,omitempty"`
+	Fit            *FitDecision `json:"fit,omitempty"`
+	Words          []WordTiming `json:"words,omitempty"` // relative to the start of the synthesized audio
+	AudioBytes     []byte       `json:"-"`
+	AudioFormat    string       `json:"-"`
+}
+
+// MixSettings controls how narration sits over background music. Volumes are
+// linear gain; zero fields in a request override keep the preset's value.
+type MixSettings struct {
+	VoiceVolume   float64 `json:"voiceVolume,omitempty"`
+	MusicBed      float64 `json:"musicBed,omitempty"`      // music level between lines
+	DuckThreshold float64 `json:"duckThreshold,omitempty"` // voice level that starts ducking (0-1)
+	DuckRatio     float64 `json:"duckRatio,omitempty"`     // compression applied to music while voice plays (1-20)
+	AttackMs      float64 `json:"attackMs,omitempty"`      // how fast music drops when a line starts
+	ReleaseMs     float64 `json:"releaseMs,omitempty"`     // how fast music recovers after a line
+	FadeSec       float64 `json:"fadeSec,omitempty"`       // crossfade at music style changes and at the ends
+}
+
+// LoudnessStats are EBU R128 measurements reported by ffmpeg's loudnorm filter
+type LoudnessStats struct {
+	InputI       float64 `json:"inputI"` // integrated loudness before normalization, LUFS
+	InputTP      float64 `json:"inputTP"`
+	InputLRA     float64 `json:"inputLRA"`
+	InputThresh  float64 `json:"inputThresh"`
+	OutputI      float64 `json:"outputI,omitempty"` // after normalization; reported for the final mix
+	OutputTP     float64 `json:"outputTP,omitempty"`
+	OutputLRA    float64 `json:"outputLRA,omitempty"`
+	TargetOffset float64 `json:"targetOffset"`
+}
+
+// LoudnessReport describes the loudness normalization applied to a mix
+type LoudnessReport struct {
+	TargetLUFS float64          `json:"targetLufs"`
+	TruePeak   float64          `json:"truePeak"`
+	Mix        *LoudnessStats   `json:"mix,omitempty"`
+	Chunks     []*LoudnessStats `json:"chunks,omitempty"` // per audio chunk, in order; null where measurement failed
+}
+
+// FailedChunk is a narration chunk that could not be synthesized and is missing from the mix
+type FailedChunk struct {
+	Index       int     `json:"index"` // position in the pipeline's chunk list
 	WindowIndex int     `json:"windowIndex"`
 	Start       float64 `json:"start"`
 	End         float64 `json:"end"`
-	Duration    float64 `json:"duration"`
 	Text        string  `json:"text"`
-	Provider    string  `json:"provider"`
-	MusicStyle  string  `json:"musicStyle,omitempty"`
-	AudioURL    string  `json:"audioUrl,omitempty"`
-	AudioBytes  []byte  `json:"-"`
+	Attempts    int     `json:"attempts"`
+	Error       string  `json:"error"`
+}
+
+// FitDecision records how a synthesized chunk was made to fit its time slot
+type FitDecision struct {
+	Strategy     string  `json:"strategy"`           // "none" | "shift" | "stretch" | "rewrite" | "trim"
+	RawDuration  float64 `json:"rawDuration"`        // synthesized length before fitting
+	SlotDuration float64 `json:"slotDuration"`       // time available before the next chunk
+	Tempo        float64 `json:"tempo,omitempty"`    // atempo factor, >1 speeds up
+	ShiftSec     float64 `json:"shiftSec,omitempty"` // how far the start moved earlier into silence
+	Rewrites     int     `json:"rewrites,omitempty"` // LLM shortening rounds applied
+	Trimmed      bool    `json:"trimmed,omitempty"`  // audio was cut because nothing else fit
+}
+
+// WordTiming is the position of one spoken word within synthesized audio
+type WordTiming struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
 }
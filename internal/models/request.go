@@ -1,6 +1,10 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"path/filepath"
+)
+
 // BoundingBox represents element position and dimensions
 type BoundingBox struct {
 	X      float64 `json:"x"`
@@ -23,16 +27,81 @@ type DeepgramResult struct {
 	Words []DeepgramWord `json:"words"`
 }
 
+// Viewport is the visible page area in CSS pixels
+type Viewport struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// ScrollPosition is the page scroll offset in CSS pixels
+type ScrollPosition struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Point is a pointer position in viewport CSS pixels
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// EventMetadata is the page state the recorder attaches to each event
+type EventMetadata struct {
+	URL            string          `json:"url,omitempty"`
+	Title          string          `json:"title,omitempty"` // document title, when the recorder sends it
+	Viewport       *Viewport       `json:"viewport,omitempty"`
+	ScrollPosition *ScrollPosition `json:"scrollPosition,omitempty"`
+}
+
 // DomEvent represents a user interaction with the DOM
 type DomEvent struct {
 	Type      string                 `json:"type"`
 	Timestamp int64                  `json:"timestamp"`
 	Target    map[string]interface{} `json:"target"`
 	Bounds    *BoundingBox           `json:"bounds,omitempty"`
+	Metadata  *EventMetadata         `json:"metadata,omitempty"`
+	Pointer   *Point                 `json:"pointer,omitempty"` // "mousemove" samples only
+}
+
+// ProcessingOptions are per-request pipeline settings accepted by both request formats
+type ProcessingOptions struct {
+	Mode        string `json:"mode,omitempty"`        // "llm" (default) | "deterministic": script without any network call
+	TTSProvider string `json:"ttsProvider,omitempty"` // "deepgram" | "elevenlabs" | "openai" | "piper" | "espeak"
+	Voice       string `json:"voice,omitempty"`       // provider-specific voice; empty uses the configured default
+
+	// Narration prompt preset (see GET /presets) and the variables it can use
+	Preset   string `json:"preset,omitempty"`
+	Topic    string `json:"topic,omitempty"`
+	Audience string `json:"audience,omitempty"`
+	Product  string `json:"productName,omitempty"`
+	Language string `json:"language,omitempty"`
+
+	Mix        *MixSettings `json:"mix,omitempty"`        // overrides the preset's music ducking and fades
+	TargetLUFS float64      `json:"targetLufs,omitempty"` // integrated loudness of the final mix, e.g. -16 web, -14 YouTube
+
+	Tenant string `json:"tenant,omitempty"` // selects the content policy file; empty uses the default policy
+
+	ValidationMode string `json:"validationMode,omitempty"` // "warn" (default) | "strict": fail with 422 on errors
+	Repair         bool   `json:"repair,omitempty"`         // auto-repair validation failures before mixing
+
+	CompressDeadTime bool `json:"compressDeadTime,omitempty"` // speed up or cut idle stretches; all output times follow the compressed video
+
+	VideoPath string `json:"videoPath,omitempty"` // original recording; submitted jobs queue an MP4 render once narration is done
+}
+
+// SessionFileName is the name, without extension, a session's output files are
+// saved under. It is empty when the ID has no usable name, such as "" or "..".
+func SessionFileName(sessionID string) string {
+	name := filepath.Base(sessionID)
+	if sessionID == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+		return ""
+	}
+	return name
 }
 
 // ProcessingRequest is the main input payload
 type ProcessingRequest struct {
+	ProcessingOptions
 	SessionID            string          `json:"sessionId"`
 	RecordingStartTimeMs int64           `json:"recordingStartTimeMs"`
 	VideoDurationSec     float64         `json:"videoDurationSec"`
@@ -42,6 +111,7 @@ type ProcessingRequest struct {
 
 // RawProcessingRequest allows sending the raw JSON from files directly
 type RawProcessingRequest struct {
+	ProcessingOptions
 	DeepgramRaw json.RawMessage `json:"deepgramRaw"`
 	DomRaw      json.RawMessage `json:"domRaw"`
 }
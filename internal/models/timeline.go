@@ -10,4 +10,30 @@ type TimelineItem struct {
 	Action         string                 `json:"action,omitempty"`
 	Target         map[string]interface{} `json:"target,omitempty"`
 	Bounds         *BoundingBox           `json:"bounds,omitempty"`
+
+	// Page state at the time of the action
+	URL            string        `json:"url,omitempty"`
+	Viewport       *Viewport     `json:"viewport,omitempty"`
+	ViewportBounds *BoundingBox  `json:"viewportBounds,omitempty"` // Bounds as fractions of the viewport
+	Scroll         *ScrollMotion `json:"scroll,omitempty"`         // scroll actions only
+}
+
+// ScrollMotion summarizes one burst of scrolling
+type ScrollMotion struct {
+	From      ScrollPosition `json:"from"`
+	To        ScrollPosition `json:"to"`
+	Direction string         `json:"direction"`         // "down" | "up" | "left" | "right" | "none"
+	Distance  float64        `json:"distance"`          // CSS pixels along Direction
+	Screens   float64        `json:"screens,omitempty"` // Distance in viewport heights (or widths)
+}
+
+// Edit is one entry of an edit decision list, mapping a span of the source
+// recording onto the compressed output timeline
+type Edit struct {
+	Action      string  `json:"action"` // "keep" | "speed" | "cut"
+	SourceStart float64 `json:"sourceStart"`
+	SourceEnd   float64 `json:"sourceEnd"`
+	OutputStart float64 `json:"outputStart"`
+	OutputEnd   float64 `json:"outputEnd"`
+	Speed       float64 `json:"speed,omitempty"` // playback rate of a "speed" edit
 }
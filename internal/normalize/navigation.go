@@ -0,0 +1,148 @@
+package normalize
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+const (
+	NavigationCollapseSec = 1.0 // redirects land within this of the first URL change
+)
+
+// navigationItem builds a navigation action for a URL change seen in event metadata
+func navigationItem(t float64, from string, page pageState) models.TimelineItem {
+	item := models.TimelineItem{
+		T:        t,
+		Kind:     "action",
+		Action:   "navigation",
+		URL:      page.url,
+		Viewport: page.viewport,
+	}
+	describeNavigation(&item, from, page)
+	return item
+}
+
+// describeNavigation fills the target of a navigation action with from/to URLs,
+// the page title and a label the effect and script generators can show
+func describeNavigation(item *models.TimelineItem, from string, page pageState) {
+	if item.Target == nil {
+		item.Target = map[string]interface{}{}
+	}
+	t := item.Target
+
+	to := page.url
+	for _, k := range []string{"to", "url", "href"} {
+		if s, ok := t[k].(string); ok && s != "" {
+			to = s
+			break
+		}
+	}
+	if s, ok := t["from"].(string); ok && s != "" {
+		from = s
+	}
+
+	t["to"] = to
+	if from != "" && from != to {
+		t["from"] = from
+		t["kind"] = navigationKind(from, to)
+	}
+	if page.title != "" && to == page.url {
+		t["title"] = page.title
+	}
+	if _, ok := t["label"]; !ok {
+		if label := navigationLabel(to, page.title); label != "" {
+			t["label"] = label
+		}
+	}
+	item.URL = to
+}
+
+// appendNavigation adds a navigation, merging it into one that just happened
+// so redirect chains read as a single page change
+func appendNavigation(out []models.TimelineItem, nav models.TimelineItem) []models.TimelineItem {
+	for i := len(out) - 1; i >= 0; i-- {
+		prev := &out[i]
+		if nav.T-prev.T > NavigationCollapseSec {
+			break
+		}
+		if prev.Action != "navigation" {
+			continue
+		}
+		if from, ok := prev.Target["from"]; ok {
+			nav.Target["from"] = from
+			if s, ok := from.(string); ok {
+				nav.Target["kind"] = navigationKind(s, nav.URL)
+			}
+		}
+		nav.T = prev.T
+		out[i] = nav
+		return out
+	}
+	return append(out, nav)
+}
+
+// navigationKind is "route" for in-page (hash) routing and "page" otherwise
+func navigationKind(from, to string) string {
+	f, err1 := url.Parse(from)
+	t, err2 := url.Parse(to)
+	if err1 == nil && err2 == nil && f.Host == t.Host && f.Path == t.Path && f.RawQuery == t.RawQuery {
+		return "route"
+	}
+	return "page"
+}
+
+// navigationLabel names a destination for viewers: the page title when known,
+// otherwise something readable derived from the URL
+func navigationLabel(rawURL, title string) string {
+	if title = strings.TrimSpace(title); title != "" {
+		// "Deals | Shop" → "Deals"
+		if i := strings.IndexAny(title, "|–—"); i > 0 {
+			title = strings.TrimSpace(title[:i])
+		}
+		return title
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	q := u.Query()
+	for _, k := range []string{"q", "query", "search", "k"} {
+		if v := strings.TrimSpace(q.Get(k)); v != "" {
+			return "search results for " + v
+		}
+	}
+
+	// SPA hash routes ("#/settings/profile") carry the meaningful path
+	p := u.Path
+	if strings.HasPrefix(u.Fragment, "/") {
+		p = u.Fragment
+	}
+	if seg := path.Base(strings.TrimSuffix(p, "/")); seg != "" && seg != "." && seg != "/" {
+		if label := humanize(seg); label != "" {
+			return label
+		}
+	}
+
+	return strings.TrimPrefix(u.Hostname(), "www.") + " home page"
+}
+
+// humanize turns a URL segment like "mobile-phones_store.html" into "mobile phones store"
+func humanize(seg string) string {
+	seg = strings.TrimSuffix(seg, path.Ext(seg))
+	if unescaped, err := url.PathUnescape(seg); err == nil {
+		seg = unescaped
+	}
+	seg = strings.NewReplacer("-", " ", "_", " ", "+", " ").Replace(seg)
+	seg = strings.Join(strings.Fields(seg), " ")
+
+	// IDs and hashes make poor labels
+	if strings.IndexFunc(seg, func(r rune) bool { return r < '0' || r > '9' }) < 0 || len(seg) > 40 {
+		return ""
+	}
+	return seg
+}
@@ -0,0 +1,119 @@
+package normalize
+
+import (
+	"math"
+
+	"godemo/internal/models"
+)
+
+const (
+	MinScrollDistancePx = 8.0 // smaller movements are treated as no scroll
+)
+
+// pageState is the latest URL, viewport and scroll position seen in the event stream.
+// Events without metadata inherit it from earlier ones.
+type pageState struct {
+	url      string
+	title    string
+	viewport *models.Viewport
+	scroll   *models.ScrollPosition
+}
+
+func (p *pageState) update(meta *models.EventMetadata) {
+	if meta == nil {
+		return
+	}
+	if meta.URL != "" {
+		p.navigate(meta.URL)
+	}
+	if meta.Title != "" {
+		p.title = meta.Title
+	}
+	if meta.Viewport != nil && meta.Viewport.Width > 0 && meta.Viewport.Height > 0 {
+		p.viewport = meta.Viewport
+	}
+	if meta.ScrollPosition != nil {
+		p.scroll = meta.ScrollPosition
+	}
+}
+
+// navigate moves to a new URL, forgetting the previous page's title
+func (p *pageState) navigate(url string) {
+	if url != p.url {
+		p.url = url
+		p.title = ""
+	}
+}
+
+// newScroll starts a scroll burst at the position before the first scroll event
+func newScroll(from *models.ScrollPosition, page pageState) *models.ScrollMotion {
+	m := &models.ScrollMotion{Direction: "none"}
+	if page.scroll != nil {
+		m.To = *page.scroll
+		m.From = m.To
+	}
+	if from != nil {
+		m.From = *from
+	}
+	measureScroll(m, page.viewport)
+	return m
+}
+
+// extendScroll folds a debounced scroll event into its burst
+func extendScroll(m *models.ScrollMotion, page pageState) {
+	if m == nil || page.scroll == nil {
+		return
+	}
+	m.To = *page.scroll
+	measureScroll(m, page.viewport)
+}
+
+// measureScroll derives direction and distance from the burst's end points
+func measureScroll(m *models.ScrollMotion, vp *models.Viewport) {
+	dx := m.To.X - m.From.X
+	dy := m.To.Y - m.From.Y
+
+	m.Direction = "none"
+	m.Distance = 0
+	m.Screens = 0
+
+	var screen float64
+	switch {
+	case math.Abs(dy) >= math.Abs(dx) && math.Abs(dy) >= MinScrollDistancePx:
+		m.Distance = math.Abs(dy)
+		m.Direction = "down"
+		if dy < 0 {
+			m.Direction = "up"
+		}
+		if vp != nil {
+			screen = vp.Height
+		}
+	case math.Abs(dx) >= MinScrollDistancePx:
+		m.Distance = math.Abs(dx)
+		m.Direction = "right"
+		if dx < 0 {
+			m.Direction = "left"
+		}
+		if vp != nil {
+			screen = vp.Width
+		}
+	}
+
+	if screen > 0 {
+		m.Screens = math.Round(m.Distance/screen*10) / 10
+	}
+}
+
+// viewportBounds expresses element bounds as fractions of the viewport.
+// Elements partly off screen produce values outside 0-1.
+func viewportBounds(b *models.BoundingBox, vp *models.Viewport) *models.BoundingBox {
+	if b == nil || vp == nil || b.Width <= 0 || b.Height <= 0 {
+		return nil
+	}
+	return &models.BoundingBox{
+		X:      b.X / vp.Width,
+		Y:      b.Y / vp.Height,
+		Width:  b.Width / vp.Width,
+		Height: b.Height / vp.Height,
+	}
+}
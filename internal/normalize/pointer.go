@@ -0,0 +1,35 @@
+package normalize
+
+import (
+	"godemo/internal/models"
+)
+
+// PointerSamples extracts recorded "mousemove" positions as viewport fractions,
+// on the same clock as NormalizeDomEvents. Samples recorded before any viewport
+// size is known are skipped.
+func PointerSamples(events []models.DomEvent, startMs int64, videoDuration float64) []models.CursorPoint {
+	var out []models.CursorPoint
+	var page pageState
+
+	for _, e := range events {
+		page.update(e.Metadata)
+		if e.Type != "mousemove" || e.Pointer == nil || page.viewport == nil || e.Timestamp == 0 {
+			continue
+		}
+
+		t := float64(e.Timestamp-startMs) / 1000
+		if t < 0 || t > videoDuration {
+			continue
+		}
+		out = append(out, models.CursorPoint{
+			T: t,
+			X: clampUnit(e.Pointer.X / page.viewport.Width),
+			Y: clampUnit(e.Pointer.Y / page.viewport.Height),
+		})
+	}
+	return out
+}
+
+func clampUnit(v float64) float64 {
+	return min(max(v, 0), 1)
+}
@@ -0,0 +1,366 @@
+package normalize
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+// Kinds of personal data that are masked
+const (
+	PIIEmail    = "email"
+	PIIPhone    = "phone"
+	PIICard     = "card"
+	PIIPassword = "password"
+)
+
+const (
+	BlurLeadSec  = 0.5 // start blurring a field slightly before typing is recorded
+	BlurStrength = 12  // boxblur radius in pixels
+)
+
+var piiMasks = map[string]string{
+	PIIEmail:    "[email]",
+	PIIPhone:    "[phone]",
+	PIICard:     "[card]",
+	PIIPassword: "[redacted]",
+}
+
+var (
+	emailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+	spokenEmailPattern = regexp.MustCompile(`(?i)\b[a-z0-9._-]+(?: dot [a-z0-9_-]+)* at [a-z0-9-]+(?: dot [a-z0-9-]+)* dot (?:com|org|net|io|co|edu|gov|in|uk|de)\b`)
+	cardPattern        = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+	// Phone numbers need phone-like grouping: an international prefix, an area
+	// code in parentheses or 3-3-4 groups. Bare digit runs are usually IDs.
+	phonePattern         = regexp.MustCompile(`\+\d{1,3}(?:[\s.-]?\(\d{1,4}\))?(?:[\s.-]?\d{1,4}){2,5}|\(\d{3}\)[\s.-]?\d{3}[\s.-]\d{4}|\d{3}[\s.-]\d{3}[\s.-]\d{4}`)
+	datePattern          = regexp.MustCompile(`^\+?\d{4}[\s./-]\d{1,2}[\s./-]\d{1,2}(?:\D|$)`)
+	sensitiveNamePattern = regexp.MustCompile(`(?i)pass(word|wd)|cvv|cvc|card.?number|\bssn\b|one.?time.?code`)
+)
+
+// Target keys that describe the element rather than what the user typed or saw
+var structuralKeys = map[string]bool{
+	"tag":         true,
+	"selector":    true,
+	"cssSelector": true,
+	"classes":     true,
+	"rrwebId":     true,
+	"id":          true,
+	"bbox":        true,
+}
+
+// Redaction records one masked value. The value itself is never kept.
+type Redaction struct {
+	Kind   string  `json:"kind"`   // "email" | "phone" | "card" | "password"
+	Source string  `json:"source"` // "action" | "transcript"
+	Index  int     `json:"index"`  // action or transcript word index
+	Field  string  `json:"field,omitempty"`
+	T      float64 `json:"t"`
+}
+
+// piiMatch is one detected span of a string
+type piiMatch struct {
+	start, end int
+	kind       string
+}
+
+// RedactActions masks personal data in action targets and URLs before they reach
+// the LLM prompt, the instructions or the response. Password and card fields are
+// masked whole by element type; other text is scanned for emails, card numbers
+// and phone numbers. The input items are not modified.
+func RedactActions(actions []models.TimelineItem) ([]models.TimelineItem, []Redaction) {
+	out := make([]models.TimelineItem, len(actions))
+	var redactions []Redaction
+
+	for i, a := range actions {
+		record := func(kind, field string) {
+			redactions = append(redactions, Redaction{Kind: kind, Source: "action", Index: i, Field: field, T: a.T})
+		}
+
+		if a.Target != nil {
+			a.Target = redactTarget(a.Target, sensitiveField(a.Target), "", record)
+		}
+		if masked, kinds := maskText(a.URL); len(kinds) > 0 {
+			a.URL = masked
+			for _, k := range kinds {
+				record(k, "url")
+			}
+		}
+		out[i] = a
+	}
+	return out, redactions
+}
+
+// redactTarget copies a target map with every user-visible string masked.
+// Nested maps such as "attributes" are scanned too.
+func redactTarget(target map[string]interface{}, sensitive bool, prefix string, record func(kind, field string)) map[string]interface{} {
+	out := make(map[string]interface{}, len(target))
+	for k, v := range target {
+		field := prefix + k
+		switch val := v.(type) {
+		case string:
+			if structuralKeys[k] || val == "" {
+				out[k] = val
+				continue
+			}
+			if sensitive && (k == "value" || k == "text") {
+				out[k] = piiMasks[PIIPassword]
+				record(PIIPassword, field)
+				continue
+			}
+			masked, kinds := maskText(val)
+			for _, kind := range kinds {
+				record(kind, field)
+			}
+			out[k] = masked
+		case map[string]interface{}:
+			if structuralKeys[k] {
+				out[k] = val
+				continue
+			}
+			out[k] = redactTarget(val, sensitive, field+".", record)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// sensitiveField reports whether the element holds a secret by its type,
+// autocomplete hint or name, whatever its value looks like
+func sensitiveField(target map[string]interface{}) bool {
+	attrs, _ := target["attributes"].(map[string]interface{})
+	get := func(k string) string {
+		if s, ok := target[k].(string); ok && s != "" {
+			return s
+		}
+		s, _ := attrs[k].(string)
+		return s
+	}
+
+	switch strings.ToLower(get("type")) {
+	case "password":
+		return true
+	}
+	if ac := strings.ToLower(get("autocomplete")); strings.HasPrefix(ac, "cc-") || strings.Contains(ac, "password") || ac == "one-time-code" {
+		return true
+	}
+	for _, k := range []string{"name", "id", "ariaLabel", "aria-label", "placeholder"} {
+		if sensitiveNamePattern.MatchString(get(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactTranscript masks personal data spoken in the recording. Words making up
+// one match are collapsed into a single masked word spanning their time, so the
+// timeline keeps its shape. The input transcript is not modified.
+func RedactTranscript(dg *models.DeepgramResult) (*models.DeepgramResult, []Redaction) {
+	if dg == nil || len(dg.Words) == 0 {
+		return dg, nil
+	}
+
+	// Scan the punctuated transcript, remembering where each word sits in it
+	var b strings.Builder
+	offsets := make([]int, len(dg.Words)+1)
+	for i, w := range dg.Words {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		offsets[i] = b.Len()
+		b.WriteString(wordText(w))
+	}
+	offsets[len(dg.Words)] = b.Len() + 1
+
+	matches := findPII(b.String())
+	if len(matches) == 0 {
+		return dg, nil
+	}
+
+	out := *dg
+	out.Words = nil
+	var redactions []Redaction
+	m := 0
+	for i := 0; i < len(dg.Words); i++ {
+		for m < len(matches) && matches[m].end <= offsets[i] {
+			m++
+		}
+		w := dg.Words[i]
+		if m == len(matches) || offsets[i+1]-1 <= matches[m].start {
+			out.Words = append(out.Words, w)
+			continue
+		}
+
+		// Fold every word the match touches into the first one
+		last := i
+		for last+1 < len(dg.Words) && offsets[last+1] < matches[m].end {
+			last++
+		}
+		mask := piiMasks[matches[m].kind]
+		w.Word, w.PunctuatedWord = mask, mask
+		w.End = math.Max(w.End, dg.Words[last].End)
+		out.Words = append(out.Words, w)
+		redactions = append(redactions, Redaction{Kind: matches[m].kind, Source: "transcript", Index: i, T: w.Start})
+		i = last
+	}
+	return &out, redactions
+}
+
+// BlurEffects hides fields whose content was redacted in the rendered video.
+// A blur covers the field from just before the action until the page scrolls
+// or navigates, after which its recorded bounds no longer apply.
+func BlurEffects(actions []models.TimelineItem, redactions []Redaction, videoDuration float64) []models.DisplayEffect {
+	seen := map[int]bool{}
+	var fx []models.DisplayEffect
+	for _, r := range redactions {
+		if r.Source != "action" || r.Field == "url" || seen[r.Index] || r.Index >= len(actions) {
+			continue
+		}
+		seen[r.Index] = true
+
+		a := actions[r.Index]
+		if a.Bounds == nil || a.Bounds.Width <= 0 || a.Bounds.Height <= 0 {
+			continue
+		}
+		end := videoDuration
+		for _, next := range actions[r.Index+1:] {
+			if next.T > a.T && (next.Action == "scroll" || next.Action == "navigation") {
+				end = next.T
+				break
+			}
+		}
+		start := math.Max(a.T-BlurLeadSec, 0)
+		if end <= start {
+			continue
+		}
+
+		selector, _ := a.Target["selector"].(string)
+		fx = append(fx, models.DisplayEffect{
+			Start: start,
+			End:   end,
+			Type:  "blur",
+			Target: &models.EffectTarget{
+				Selector: selector,
+				Bounds:   a.Bounds,
+
+				ViewportBounds: a.ViewportBounds,
+			},
+			Style: map[string]interface{}{
+				"strength": BlurStrength,
+			},
+		})
+	}
+	return fx
+}
+
+// maskText replaces every detected value in s with its mask
+func maskText(s string) (string, []string) {
+	matches := findPII(s)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	var kinds []string
+	prev := 0
+	for _, m := range matches {
+		b.WriteString(s[prev:m.start])
+		b.WriteString(piiMasks[m.kind])
+		kinds = append(kinds, m.kind)
+		prev = m.end
+	}
+	b.WriteString(s[prev:])
+	return b.String(), kinds
+}
+
+// findPII returns non-overlapping detections in s, ordered by position.
+// Emails win over card numbers, which win over phone numbers.
+func findPII(s string) []piiMatch {
+	var found []piiMatch
+	add := func(re *regexp.Regexp, kind string, valid func(string) bool) {
+		for _, loc := range re.FindAllStringIndex(s, -1) {
+			start, end := loc[0], loc[1]
+			if !boundary(s, start, end) || !valid(s[start:end]) {
+				continue
+			}
+			overlaps := false
+			for _, f := range found {
+				if start < f.end && f.start < end {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				found = append(found, piiMatch{start, end, kind})
+			}
+		}
+	}
+	always := func(string) bool { return true }
+
+	add(emailPattern, PIIEmail, always)
+	add(spokenEmailPattern, PIIEmail, always)
+	add(cardPattern, PIICard, func(m string) bool { return luhn(digits(m)) })
+	add(phonePattern, PIIPhone, func(m string) bool {
+		n := len(digits(m))
+		return n >= 7 && n <= 15 && !datePattern.MatchString(m)
+	})
+
+	sort.Slice(found, func(i, j int) bool { return found[i].start < found[j].start })
+	return found
+}
+
+// boundary rejects matches that start or end inside a longer number or word,
+// including times and dotted numbers such as 10:30 or 2024.01.15.1030
+func boundary(s string, start, end int) bool {
+	digit := func(c byte) bool { return c >= '0' && c <= '9' }
+	alnum := func(c byte) bool { return digit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+	joint := func(c byte) bool { return c == ':' || c == '.' || c == '/' || c == '-' }
+
+	if start > 0 && (alnum(s[start-1]) || start > 1 && joint(s[start-1]) && digit(s[start-2])) {
+		return false
+	}
+	if end < len(s) && (alnum(s[end]) || end+1 < len(s) && joint(s[end]) && digit(s[end+1])) {
+		return false
+	}
+	return true
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// luhn validates a card number checksum
+func luhn(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func wordText(w models.DeepgramWord) string {
+	if w.PunctuatedWord != "" {
+		return w.PunctuatedWord
+	}
+	return w.Word
+}
@@ -0,0 +1,145 @@
+package normalize
+
+import (
+	"reflect"
+	"testing"
+
+	"godemo/internal/models"
+)
+
+func TestMaskText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// Emails
+		{"contact jane.doe+demo@example.co.uk now", "contact [email] now"},
+		{"write to john dot smith at gmail dot com", "write to [email]"},
+
+		// Cards pass only with a valid checksum
+		{"card 4111 1111 1111 1111 saved", "card [card] saved"},
+		{"card 4111-1111-1111-1111", "card [card]"},
+		{"card 4111 1111 1111 1112", "card 4111 1111 1111 1112"},
+
+		// Phones need phone-like grouping
+		{"call +1 (555) 123-4567", "call [phone]"},
+		{"call (555) 123-4567 today", "call [phone] today"},
+		{"call 555-123-4567.", "call [phone]."},
+		{"call 555.123.4567", "call [phone]"},
+		{"ring +44 20 7946 0958", "ring [phone]"},
+
+		// Dates, times and IDs are left alone
+		{"Updated 2024-01-15 10:30", "Updated 2024-01-15 10:30"},
+		{"/app/orders/1234567890", "/app/orders/1234567890"},
+		{"Invoice 2024.01.15.1030", "Invoice 2024.01.15.1030"},
+		{"Order 5551234567", "Order 5551234567"},
+		{"+2024-01-15", "+2024-01-15"},
+		{"build 555-123-4567-89", "build 555-123-4567-89"},
+		{"v1.2.3", "v1.2.3"},
+	}
+	for _, tt := range tests {
+		if got, _ := maskText(tt.in); got != tt.want {
+			t.Errorf("maskText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFindPIIKinds(t *testing.T) {
+	got := findPII("a@b.io, 555-123-4567 and 4111111111111111")
+	var kinds []string
+	for _, m := range got {
+		kinds = append(kinds, m.kind)
+	}
+	if want := []string{PIIEmail, PIIPhone, PIICard}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"5500005555555559", true},
+		{"378282246310005", true},
+		{"4111111111111112", false},
+		{"411111111111", false},         // too short
+		{"41111111111111111111", false}, // too long
+	}
+	for _, tt := range tests {
+		if got := luhn(tt.number); got != tt.want {
+			t.Errorf("luhn(%q) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestRedactTranscript(t *testing.T) {
+	word := func(w string, start float64) models.DeepgramWord {
+		return models.DeepgramWord{Word: w, PunctuatedWord: w, Start: start, End: start + 0.4}
+	}
+	tests := []struct {
+		name  string
+		words []models.DeepgramWord
+		want  []string
+		kinds []string
+		end   float64 // end of the masked word
+	}{
+		{
+			name:  "single word",
+			words: []models.DeepgramWord{word("email", 0), word("jane@example.com", 1), word("now", 2)},
+			want:  []string{"email", "[email]", "now"},
+			kinds: []string{PIIEmail},
+			end:   1.4,
+		},
+		{
+			name: "spoken email folds",
+			words: []models.DeepgramWord{
+				word("it's", 0), word("john", 1), word("at", 2), word("gmail", 3), word("dot", 4), word("com", 5), word("thanks", 6),
+			},
+			want:  []string{"it's", "[email]", "thanks"},
+			kinds: []string{PIIEmail},
+			end:   5.4,
+		},
+		{
+			name:  "grouped phone folds",
+			words: []models.DeepgramWord{word("call", 0), word("(555)", 1), word("123-4567", 2)},
+			want:  []string{"call", "[phone]"},
+			kinds: []string{PIIPhone},
+			end:   2.4,
+		},
+		{
+			name:  "nothing to mask",
+			words: []models.DeepgramWord{word("meeting", 0), word("at", 1), word("10:30", 2)},
+			want:  []string{"meeting", "at", "10:30"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &models.DeepgramResult{Words: tt.words}
+			out, redactions := RedactTranscript(in)
+
+			var got []string
+			for _, w := range out.Words {
+				got = append(got, w.PunctuatedWord)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("words = %v, want %v", got, tt.want)
+			}
+
+			var kinds []string
+			for _, r := range redactions {
+				kinds = append(kinds, r.Kind)
+				if masked := out.Words[r.Index]; masked.End != tt.end {
+					t.Errorf("masked word ends at %v, want %v", masked.End, tt.end)
+				}
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("kinds = %v, want %v", kinds, tt.kinds)
+			}
+			if in.Words[1].Word != tt.words[1].Word {
+				t.Errorf("input transcript was modified")
+			}
+		})
+	}
+}
@@ -19,14 +19,11 @@ func NormalizeDomEvents(
 
 	var out []models.TimelineItem
 	var lastScrollTime float64 = -100.0
+	var page pageState
+	scrollIdx := -1 // item the current scroll burst is folded into
 
 	for _, e := range events {
-		// 1. Ignore noise
-		if e.Type == "" || e.Type == "dom_mutation" {
-			continue
-		}
-
-		if e.Timestamp == 0 {
+		if e.Type == "" || e.Timestamp == 0 {
 			continue
 		}
 
@@ -34,22 +31,56 @@ func NormalizeDomEvents(
 		if t < 0 { t = 0 }
 		if t > videoDuration { t = videoDuration }
 
+		prevScroll := page.scroll
+		prevURL := page.url
+		page.update(e.Metadata)
+
+		// 1. Derive navigation from URL changes; recorders rarely fire an explicit
+		// event for them. Noise events still carry the page metadata.
+		if prevURL != "" && page.url != prevURL && e.Type != "navigation" {
+			out = appendNavigation(out, navigationItem(t, prevURL, page))
+		}
+
+		// Ignore noise; pointer samples are read separately by PointerSamples
+		if e.Type == "dom_mutation" || e.Type == "mousemove" {
+			continue
+		}
+
 		// 2. Collapse consecutive scrolls (debounce)
 		// If we get multiple scrolls within 1.5 seconds, treat them as one continuous action
 		if e.Type == "scroll" {
 			if t-lastScrollTime < 1.5 {
+				if scrollIdx >= 0 {
+					extendScroll(out[scrollIdx].Scroll, page)
+				}
 				continue 
 			}
 			lastScrollTime = t
 		}
 
-		out = append(out, models.TimelineItem{
+		item := models.TimelineItem{
 			T:      t,
 			Kind:   "action",
 			Action: e.Type,
 			Target: e.Target,
 			Bounds: e.Bounds,
-		})
+
+			URL:            page.url,
+			Viewport:       page.viewport,
+			ViewportBounds: viewportBounds(e.Bounds, page.viewport),
+		}
+		if e.Type == "scroll" {
+			item.Scroll = newScroll(prevScroll, page)
+			scrollIdx = len(out)
+		}
+		if e.Type == "navigation" {
+			describeNavigation(&item, prevURL, page)
+			page.navigate(item.URL)
+			out = appendNavigation(out, item)
+			continue
+		}
+
+		out = append(out, item)
 	}
 
 	return out, nil
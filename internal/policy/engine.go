@@ -0,0 +1,223 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"godemo/internal/models"
+)
+
+var (
+	multiSpace      = regexp.MustCompile(`\s{2,}`)
+	spaceBeforePunc = regexp.MustCompile(`\s+([,.;:!?])`)
+	repeatedPunc    = regexp.MustCompile(`([,;:])\s*([,.;:!?])`)
+)
+
+// Finding is one rule hit on one narration
+type Finding struct {
+	Rule    string `json:"rule"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Index   int    `json:"index"` // narration index, -1 for script-wide rules
+	Match   string `json:"match,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report lists what a policy found and changed in a script
+type Report struct {
+	Tenant   string    `json:"tenant"`
+	Source   string    `json:"source"`
+	Blocked  bool      `json:"blocked"` // a block rule matched; the request fails
+	Warnings int       `json:"warnings"`
+	Rewrites int       `json:"rewrites"`
+	Findings []Finding `json:"findings"`
+}
+
+func (r *Report) add(rule *Rule, index int, match, format string, args ...interface{}) {
+	msg := rule.Message
+	if msg == "" {
+		msg = fmt.Sprintf(format, args...)
+	}
+	r.Findings = append(r.Findings, Finding{
+		Rule:    rule.ID,
+		Type:    rule.Type,
+		Action:  rule.Action,
+		Index:   index,
+		Match:   match,
+		Message: msg,
+	})
+	switch rule.Action {
+	case ActionBlock:
+		r.Blocked = true
+	case ActionRewrite:
+		r.Rewrites++
+	default:
+		r.Warnings++
+	}
+}
+
+// Apply runs every rule over the narration text in order. The input slice is not
+// modified; rewrites are applied to the returned copy, so later rules see the
+// text earlier rules produced.
+func (p *Policy) Apply(narrations []models.Narration) ([]models.Narration, *Report) {
+	out := append([]models.Narration(nil), narrations...)
+	report := &Report{Tenant: p.Tenant, Source: p.Source, Findings: []Finding{}}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		switch rule.Type {
+		case TypeBanned, TypeProfanity:
+			for n := range out {
+				out[n].Text = replacePhrases(rule, report, n, out[n].Text, "%q is not allowed")
+			}
+		case TypeClaim:
+			for n := range out {
+				if matchesAny(rule.unless, out[n].Text) {
+					continue
+				}
+				out[n].Text = replacePhrases(rule, report, n, out[n].Text, "%q is a claim that needs substantiation")
+			}
+		case TypeCasing:
+			for n := range out {
+				out[n].Text = fixCasing(rule, report, n, out[n].Text)
+			}
+		case TypeDisclaimer:
+			applyDisclaimer(rule, report, out)
+		}
+	}
+	return out, report
+}
+
+// Recheck runs the policy over one narration rewritten after Apply, such as a
+// line shortened to fit its slot. Findings carry the narration's index. A
+// script-wide disclaimer is only required again when the original line carried it.
+func (p *Policy) Recheck(index int, original, rewritten string) (string, *Report) {
+	q := *p
+	q.Rules = nil
+	for _, r := range p.Rules {
+		if r.Type == TypeDisclaimer && len(r.when) == 0 && !containsFold(original, r.Text) {
+			continue
+		}
+		q.Rules = append(q.Rules, r)
+	}
+
+	out, report := q.Apply([]models.Narration{{Text: rewritten}})
+	for i := range report.Findings {
+		report.Findings[i].Index = index
+	}
+	return out[0].Text, report
+}
+
+// Merge adds the findings of a later check to r
+func (r *Report) Merge(o *Report) {
+	r.Blocked = r.Blocked || o.Blocked
+	r.Warnings += o.Warnings
+	r.Rewrites += o.Rewrites
+	r.Findings = append(r.Findings, o.Findings...)
+}
+
+// replacePhrases reports every phrase of the rule found in text and, for rewrite
+// rules, replaces it
+func replacePhrases(rule *Rule, report *Report, index int, text, format string) string {
+	changed := false
+	for _, re := range rule.phrases {
+		matches := re.FindAllString(text, -1)
+		for _, m := range matches {
+			report.add(rule, index, m, format, m)
+		}
+		if len(matches) > 0 && rule.Action == ActionRewrite {
+			text = re.ReplaceAllStringFunc(text, func(m string) string { return matchCase(rule.Replacement, m) })
+			changed = true
+		}
+	}
+	if changed {
+		text = tidy(text)
+	}
+	return text
+}
+
+// fixCasing reports terms spelled with the wrong case and, for rewrite rules, corrects them
+func fixCasing(rule *Rule, report *Report, index int, text string) string {
+	for i, re := range rule.terms {
+		term := rule.Terms[i]
+		text = re.ReplaceAllStringFunc(text, func(m string) string {
+			if m == term {
+				return m
+			}
+			report.add(rule, index, m, "%q should be written %q", m, term)
+			if rule.Action == ActionRewrite {
+				return term
+			}
+			return m
+		})
+	}
+	return text
+}
+
+// applyDisclaimer requires the disclaimer in every narration that mentions a
+// trigger phrase, or somewhere in the script when the rule has no triggers.
+// Rewrites append it to the narration that needs it, or to the last one.
+func applyDisclaimer(rule *Rule, report *Report, narrations []models.Narration) {
+	if len(narrations) == 0 {
+		return
+	}
+	text := strings.TrimSpace(rule.Text)
+	has := func(s string) bool { return containsFold(s, text) }
+	appendTo := func(n int) {
+		if rule.Action == ActionRewrite {
+			narrations[n].Text = strings.TrimSpace(narrations[n].Text) + " " + text
+		}
+	}
+
+	if len(rule.when) == 0 {
+		for _, n := range narrations {
+			if has(n.Text) {
+				return
+			}
+		}
+		report.add(rule, -1, "", "script is missing the disclaimer %q", text)
+		appendTo(len(narrations) - 1)
+		return
+	}
+
+	for n := range narrations {
+		if !matchesAny(rule.when, narrations[n].Text) || has(narrations[n].Text) {
+			continue
+		}
+		report.add(rule, n, "", "narration needs the disclaimer %q", text)
+		appendTo(n)
+	}
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(strings.TrimSpace(substr)))
+}
+
+func matchesAny(res []*regexp.Regexp, text string) bool {
+	for _, re := range res {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCase capitalizes the replacement when the phrase it replaces was capitalized
+func matchCase(replacement, match string) string {
+	if replacement == "" || match == "" || !unicode.IsUpper([]rune(match)[0]) {
+		return replacement
+	}
+	r := []rune(replacement)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// tidy cleans up the spacing and punctuation a removed phrase leaves behind
+func tidy(text string) string {
+	text = multiSpace.ReplaceAllString(text, " ")
+	text = spaceBeforePunc.ReplaceAllString(text, "$1")
+	text = repeatedPunc.ReplaceAllString(text, "$2")
+	return strings.TrimSpace(text)
+}
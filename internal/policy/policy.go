@@ -1,3 +1,198 @@
 package policy
 
-// Policy definitions and implementations
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule types
+const (
+	TypeBanned     = "banned"     // phrases that must not appear
+	TypeDisclaimer = "disclaimer" // text that must appear, optionally only when trigger phrases do
+	TypeCasing     = "casing"     // brand and product names with a fixed spelling
+	TypeClaim      = "claim"      // claims that need substantiation in the same line
+	TypeProfanity  = "profanity"  // banned phrases with a built-in default list
+)
+
+// Rule actions
+const (
+	ActionWarn    = "warn"    // report only
+	ActionRewrite = "rewrite" // fix the narration text and report the change
+	ActionBlock   = "block"   // fail the request
+)
+
+const DefaultTenant = "default"
+
+// DefaultProfanity is used by profanity rules that list no phrases of their own
+var DefaultProfanity = []string{
+	"damn", "hell", "crap", "shit", "fuck", "fucking", "bullshit", "ass", "asshole", "bitch", "bastard", "piss",
+}
+
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Rule is one entry of a tenant policy file
+type Rule struct {
+	ID          string   `json:"id" yaml:"id"`
+	Type        string   `json:"type" yaml:"type"`
+	Action      string   `json:"action" yaml:"action"`
+	Phrases     []string `json:"phrases,omitempty" yaml:"phrases,omitempty"`         // banned, claim, profanity
+	Replacement string   `json:"replacement,omitempty" yaml:"replacement,omitempty"` // rewrite text; empty removes the phrase
+	Terms       []string `json:"terms,omitempty" yaml:"terms,omitempty"`             // casing: the correct spellings
+	Text        string   `json:"text,omitempty" yaml:"text,omitempty"`               // disclaimer text
+	When        []string `json:"when,omitempty" yaml:"when,omitempty"`               // disclaimer triggers; empty requires it once per script
+	Unless      []string `json:"unless,omitempty" yaml:"unless,omitempty"`           // claim: phrases that substantiate it, e.g. "according to"
+	Message     string   `json:"message,omitempty" yaml:"message,omitempty"`
+
+	phrases, terms, when, unless []*regexp.Regexp
+}
+
+// Policy is the rule set of one tenant
+type Policy struct {
+	Tenant string `json:"tenant" yaml:"-"`
+	Source string `json:"source" yaml:"-"` // file the rules were loaded from
+	Rules  []Rule `json:"rules" yaml:"rules"`
+}
+
+// Dir returns where tenant policy files live: POLICY_DIR, default "policies"
+func Dir() string {
+	if dir := os.Getenv("POLICY_DIR"); dir != "" {
+		return dir
+	}
+	return "policies"
+}
+
+// ForTenant loads <tenant>.yaml, .yml or .json from Dir, falling back to the
+// default tenant's file. It returns nil without error when neither exists.
+func ForTenant(tenant string) (*Policy, error) {
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant %q", tenant)
+	}
+
+	for _, name := range []string{tenant, DefaultTenant} {
+		for _, ext := range []string{".yaml", ".yml", ".json"} {
+			path := filepath.Join(Dir(), name+ext)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			p, err := Load(path)
+			if err != nil {
+				return nil, err
+			}
+			p.Tenant = tenant
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Load reads and compiles a policy file; the extension selects JSON or YAML
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %v", err)
+	}
+
+	var p Policy
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &p)
+	} else {
+		err = yaml.Unmarshal(data, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	if err := p.compile(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	p.Source = filepath.Base(path)
+	return &p, nil
+}
+
+// compile checks every rule and builds its matchers
+func (p *Policy) compile() error {
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("%s-%d", r.Type, i+1)
+		}
+		if r.Action == "" {
+			r.Action = ActionWarn
+		}
+		switch r.Action {
+		case ActionWarn, ActionRewrite, ActionBlock:
+		default:
+			return fmt.Errorf("rule %s: unknown action %q", r.ID, r.Action)
+		}
+
+		switch r.Type {
+		case TypeBanned, TypeClaim:
+			if len(r.Phrases) == 0 {
+				return fmt.Errorf("rule %s: phrases required", r.ID)
+			}
+		case TypeProfanity:
+			if len(r.Phrases) == 0 {
+				r.Phrases = DefaultProfanity
+			}
+		case TypeCasing:
+			if len(r.Terms) == 0 {
+				return fmt.Errorf("rule %s: terms required", r.ID)
+			}
+		case TypeDisclaimer:
+			if strings.TrimSpace(r.Text) == "" {
+				return fmt.Errorf("rule %s: text required", r.ID)
+			}
+		default:
+			return fmt.Errorf("rule %s: unknown type %q", r.ID, r.Type)
+		}
+
+		r.Terms = nonEmpty(r.Terms)
+		r.phrases = phraseMatchers(r.Phrases)
+		r.terms = phraseMatchers(r.Terms)
+		r.when = phraseMatchers(r.When)
+		r.unless = phraseMatchers(r.Unless)
+	}
+	return nil
+}
+
+// phraseMatchers matches each phrase case-insensitively as whole words
+func phraseMatchers(phrases []string) []*regexp.Regexp {
+	var out []*regexp.Regexp
+	for _, ph := range phrases {
+		ph = strings.TrimSpace(ph)
+		if ph == "" {
+			continue
+		}
+		expr := regexp.QuoteMeta(ph)
+		if isWordChar(ph[0]) {
+			expr = `\b` + expr
+		}
+		if isWordChar(ph[len(ph)-1]) {
+			expr += `\b`
+		}
+		out = append(out, regexp.MustCompile("(?i)"+expr))
+	}
+	return out
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,231 @@
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pipeline stages, in the order they run
+const (
+	StageNormalize  = "normalizing"
+	StageTimeline   = "building_timeline"
+	StageScript     = "writing_script"
+	StagePolicy     = "checking_policy"
+	StageEffects    = "generating_effects"
+	StageSynthesize = "synthesizing_voice"
+	StageFit        = "fitting_duration"
+	StageRepair     = "repairing"
+	StageMix        = "mixing"
+	StageValidate   = "validating"
+	StageRender     = "rendering" // separate render job, after the pipeline
+)
+
+// Event types emitted on a progress stream
+const (
+	EventStageStarted  = "stage.started"
+	EventStageFinished = "stage.finished"
+	EventChunkFinished = "chunk.finished"
+	EventChunkFailed   = "chunk.failed"
+	EventError         = "error"
+	EventDone          = "done"    // terminal event; Message holds the final state
+	EventDropped       = "dropped" // terminal for one subscriber that fell behind; reconnect to resume
+)
+
+// Event is a single typed progress notification
+type Event struct {
+	Seq     int       `json:"seq"`
+	Type    string    `json:"type"`
+	Stage   string    `json:"stage,omitempty"`
+	Current int       `json:"current,omitempty"` // chunks completed so far, for chunk events
+	Total   int       `json:"total,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Emitter receives events emitted by pipeline code
+type Emitter func(Event)
+
+type emitterKey struct{}
+
+// WithEmitter returns a context whose pipeline events are delivered to fn
+func WithEmitter(ctx context.Context, fn Emitter) context.Context {
+	return context.WithValue(ctx, emitterKey{}, fn)
+}
+
+// Emit sends ev to the emitter attached to ctx, if any
+func Emit(ctx context.Context, ev Event) {
+	fn, ok := ctx.Value(emitterKey{}).(Emitter)
+	if !ok || fn == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	fn(ev)
+}
+
+// StageStarted reports that a pipeline stage began
+func StageStarted(ctx context.Context, stage string) {
+	Emit(ctx, Event{Type: EventStageStarted, Stage: stage})
+}
+
+// StageFinished reports that a pipeline stage completed, with an optional summary
+func StageFinished(ctx context.Context, stage string, message string) {
+	Emit(ctx, Event{Type: EventStageFinished, Stage: stage, Message: message})
+}
+
+// StageFailed reports that a pipeline stage stopped with an error
+func StageFailed(ctx context.Context, stage string, err error) {
+	Emit(ctx, Event{Type: EventError, Stage: stage, Error: err.Error()})
+}
+
+// Chunk reports the outcome of one item in a multi-chunk stage; current counts
+// the items completed so far, including this one
+func Chunk(ctx context.Context, stage string, current, total int, err error) {
+	ev := Event{Type: EventChunkFinished, Stage: stage, Current: current, Total: total}
+	if err != nil {
+		ev.Type = EventChunkFailed
+		ev.Error = err.Error()
+	}
+	Emit(ctx, ev)
+}
+
+// Stream buffers the events of one job or session and fans them out to subscribers.
+// Late subscribers receive the full history first.
+type Stream struct {
+	mu     sync.Mutex
+	events []Event
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// NewStream creates an open, empty stream
+func NewStream() *Stream {
+	return &Stream{subs: make(map[chan Event]struct{})}
+}
+
+// Publish appends ev to the stream and delivers it to live subscribers
+func (s *Stream) Publish(ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	ev.Seq = len(s.events) + 1
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	s.events = append(s.events, ev)
+
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+			// Slow subscriber: drop it rather than stall the pipeline
+			delete(s.subs, ch)
+			drop(ch)
+		}
+	}
+}
+
+// drop ends a subscription that fell behind with an EventDropped, so the
+// client can tell it apart from a finished stream. The oldest buffered event
+// makes room for it; a client resuming from the last event it saw gets that
+// event again from the history.
+func drop(ch chan Event) {
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- Event{Type: EventDropped, Message: "subscriber fell behind; reconnect to resume", Time: time.Now()}:
+	default:
+	}
+	close(ch)
+}
+
+// Close marks the stream finished and ends every subscription
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for ch := range s.subs {
+		close(ch)
+	}
+	s.subs = nil
+}
+
+// Subscribe returns the events published so far and a channel of later ones.
+// The channel is closed when the stream closes; call cancel to unsubscribe early.
+func (s *Stream) Subscribe() (history []Event, live <-chan Event, cancel func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history = append([]Event(nil), s.events...)
+	ch := make(chan Event, 64)
+
+	if s.closed {
+		close(ch)
+		return history, ch, func() {}
+	}
+
+	s.subs[ch] = struct{}{}
+	cancel = func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+	return history, ch, cancel
+}
+
+// Registry tracks streams by key (e.g. session ID) so they can be looked up by HTTP handlers
+type Registry struct {
+	mu      sync.Mutex
+	streams map[string]*Stream
+	linger  time.Duration
+}
+
+// NewRegistry keeps finished streams around for linger so clients can still replay them
+func NewRegistry(linger time.Duration) *Registry {
+	return &Registry{streams: make(map[string]*Stream), linger: linger}
+}
+
+// Open creates a fresh stream for key, replacing any previous one
+func (r *Registry) Open(key string) *Stream {
+	s := NewStream()
+	r.mu.Lock()
+	r.streams[key] = s
+	r.mu.Unlock()
+	return s
+}
+
+// Lookup returns the current stream for key
+func (r *Registry) Lookup(key string) (*Stream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.streams[key]
+	return s, ok
+}
+
+// Finish closes the stream and forgets it after the linger period
+func (r *Registry) Finish(key string, s *Stream) {
+	s.Close()
+	time.AfterFunc(r.linger, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.streams[key] == s {
+			delete(r.streams, key)
+		}
+	})
+}
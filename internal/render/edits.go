@@ -0,0 +1,43 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+// editFilter applies an edit decision list to the recording before any effect is
+// drawn, so effects line up with the compressed timeline. The video is first
+// resampled to a constant frame rate; kept spans pass every frame, sped-up spans
+// keep every Speed-th frame and cut spans drop theirs. Timestamps are then
+// rewritten from the surviving frame count. Returns "" when nothing is edited.
+func editFilter(edits []models.Edit, fps float64) string {
+	var terms []string
+	changed := false
+	for _, e := range edits {
+		if e.SourceEnd <= e.SourceStart {
+			continue
+		}
+		between := fmt.Sprintf("between(t,%.3f,%.3f)", e.SourceStart, e.SourceEnd)
+		switch e.Action {
+		case "keep":
+			terms = append(terms, between)
+		case "speed":
+			step := int(math.Round(e.Speed))
+			if step < 2 {
+				terms = append(terms, between)
+				continue
+			}
+			terms = append(terms, fmt.Sprintf("%s*not(mod(n,%d))", between, step))
+			changed = true
+		default:
+			changed = true
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return fmt.Sprintf("fps=%.3f,select='%s',setpts=N/(%.3f*TB)", fps, strings.Join(terms, "+"), fps)
+}
@@ -0,0 +1,367 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+const (
+	HighlightColor = "0xFFD400"
+	FocusColor     = "0x0066FF"
+	DimColor       = "black@0.45"
+	LabelBoxColor  = "black@0.55"
+	DefaultZoom    = 1.1
+	DefaultBlur    = 12 // boxblur radius in pixels
+	RippleRings    = 3
+	RippleStepPx   = 14 // each ripple ring is this much wider than the last
+)
+
+// frame maps page coordinates from effect bounds onto video pixels
+type frame struct {
+	width, height int
+	fps           float64
+	sx, sy        float64
+}
+
+func newFrame(info VideoInfo, viewportWidth, viewportHeight float64) frame {
+	f := frame{width: info.Width, height: info.Height, fps: info.FPS, sx: 1, sy: 1}
+	if viewportWidth > 0 {
+		f.sx = float64(info.Width) / viewportWidth
+		f.sy = f.sx
+	}
+	if viewportHeight > 0 {
+		f.sy = float64(info.Height) / viewportHeight
+	}
+	return f
+}
+
+// rect is an effect target in video pixels, clamped to the frame
+type rect struct{ x, y, w, h int }
+
+// rect places a target on the frame, preferring viewport-relative bounds
+// since they hold even when the viewport changed during recording
+func (f frame) rect(t *models.EffectTarget) (rect, bool) {
+	if t == nil {
+		return rect{}, false
+	}
+
+	b, sx, sy := t.Bounds, f.sx, f.sy
+	if t.ViewportBounds != nil {
+		b, sx, sy = t.ViewportBounds, float64(f.width), float64(f.height)
+	}
+	if b == nil {
+		return rect{}, false
+	}
+
+	x0 := clampInt(int(math.Round(b.X*sx)), 0, f.width)
+	y0 := clampInt(int(math.Round(b.Y*sy)), 0, f.height)
+	x1 := clampInt(int(math.Round((b.X+b.Width)*sx)), 0, f.width)
+	y1 := clampInt(int(math.Round((b.Y+b.Height)*sy)), 0, f.height)
+	if x1 <= x0 || y1 <= y0 {
+		return rect{}, false
+	}
+	return rect{x: x0, y: y0, w: x1 - x0, h: y1 - y0}, true
+}
+
+// zoomSpan is one timed zoom towards a point
+type zoomSpan struct {
+	start, end float64
+	scale      float64
+	cx, cy     float64
+}
+
+// buildFilterGraph turns display effects into a single ffmpeg video filter chain.
+// Blurs and boxes are drawn first so zoom magnifies them with the page; labels
+// are drawn last so they stay put. Label text files are written to textDir.
+func buildFilterGraph(effects []models.DisplayEffect, f frame, textDir string) (string, error) {
+	var blurs, boxes, labels []string
+	var zooms []zoomSpan
+
+	for i, e := range effects {
+		if e.End <= e.Start {
+			continue
+		}
+		enable := fmt.Sprintf("enable='between(t,%.3f,%.3f)'", e.Start, e.End)
+
+		target, hasTarget := f.rect(e.Target)
+
+		switch e.Type {
+		case "highlight":
+			if !hasTarget {
+				continue
+			}
+			if styleBool(e.Style, "dimBackground") {
+				boxes = append(boxes, dimAround(target, f, enable)...)
+			}
+			boxes = append(boxes, highlightBoxes(target, e.Style, enable)...)
+
+		case "focus":
+			if !hasTarget {
+				continue
+			}
+			if styleBool(e.Style, "dimBackground") {
+				boxes = append(boxes, dimAround(target, f, enable)...)
+			}
+			width, ok := styleFloat(e.Style, "borderWidth")
+			if !ok {
+				width = 3
+			}
+			thickness := int(math.Max(2, math.Round(width*f.sx)))
+			color := ffColor(styleString(e.Style, "borderColor"), FocusColor)
+			boxes = append(boxes, drawbox(target, color, strconv.Itoa(thickness), enable))
+
+		case "dim":
+			if hasTarget {
+				boxes = append(boxes, dimAround(target, f, enable)...)
+			}
+
+		case "click":
+			if hasTarget {
+				boxes = append(boxes, rippleBoxes(target, e.Style, e.Start, e.End)...)
+			}
+			continue
+
+		case "label":
+			text := styleString(e.Style, "text")
+			if text == "" {
+				continue
+			}
+			textFile := filepath.Join(textDir, fmt.Sprintf("label_%d.txt", i))
+			if err := os.WriteFile(textFile, []byte(text), 0644); err != nil {
+				return "", fmt.Errorf("failed to write label text: %v", err)
+			}
+			labels = append(labels, drawtext(textFile, e.Style, f, enable))
+
+		case "blur":
+			if hasTarget {
+				strength, ok := styleFloat(e.Style, "strength")
+				if !ok {
+					strength = DefaultBlur
+				}
+				blurs = append(blurs, blurRegion(len(blurs), target, int(strength), enable))
+			}
+			continue
+
+		case "zoom":
+			if hasTarget {
+				scale, ok := styleFloat(e.Style, "scale")
+				if !ok {
+					scale = DefaultZoom
+				}
+				zooms = append(zooms, newZoom(e, target, scale))
+			}
+			continue
+		}
+
+		// Highlights carry their zoom as a nested style
+		if zoom, ok := e.Style["zoom"].(map[string]interface{}); ok && hasTarget && styleBool(zoom, "enabled") {
+			scale, ok := styleFloat(zoom, "scale")
+			if !ok {
+				scale = DefaultZoom
+			}
+			zooms = append(zooms, newZoom(e, target, scale))
+		}
+	}
+
+	// Blurs come first so nothing drawn on top reveals what they hide
+	chain := append(blurs, boxes...)
+	if len(zooms) > 0 {
+		chain = append(chain, zoompan(zooms, f))
+	}
+	chain = append(chain, labels...)
+
+	// libx264 with yuv420p needs even dimensions
+	chain = append(chain, "scale=trunc(iw/2)*2:trunc(ih/2)*2")
+	return strings.Join(chain, ","), nil
+}
+
+// highlightBoxes outlines the target; a "glow" outline adds a soft halo around it
+func highlightBoxes(r rect, style map[string]interface{}, enable string) []string {
+	opacity, ok := styleFloat(style, "opacity")
+	if !ok || opacity <= 0 || opacity > 1 {
+		opacity = 0.9
+	}
+	color := fmt.Sprintf("%s@%.2f", HighlightColor, opacity)
+
+	switch styleString(style, "outline") {
+	case "glow":
+		halo := rect{x: max(r.x-6, 0), y: max(r.y-6, 0), w: r.w + 12, h: r.h + 12}
+		return []string{
+			drawbox(halo, HighlightColor+"@0.35", "6", enable),
+			drawbox(r, color, "4", enable),
+		}
+	case "soft":
+		return []string{drawbox(r, color, "3", enable)}
+	default:
+		return []string{drawbox(r, color, "4", enable)}
+	}
+}
+
+// dimAround darkens everything outside the target with four filled boxes
+func dimAround(r rect, f frame, enable string) []string {
+	regions := []rect{
+		{0, 0, f.width, r.y},                            // above
+		{0, r.y + r.h, f.width, f.height - (r.y + r.h)}, // below
+		{0, r.y, r.x, r.h},                              // left
+		{r.x + r.w, r.y, f.width - (r.x + r.w), r.h},    // right
+	}
+
+	var out []string
+	for _, reg := range regions {
+		if reg.w > 0 && reg.h > 0 {
+			out = append(out, drawbox(reg, DimColor, "fill", enable))
+		}
+	}
+	return out
+}
+
+// rippleBoxes approximates a click ripple with rings that grow from the center
+// of the target, each shown for an equal part of the effect
+func rippleBoxes(r rect, style map[string]interface{}, start, end float64) []string {
+	color := ffColor(styleString(style, "color"), "white")
+	cx, cy := r.x+r.w/2, r.y+r.h/2
+	step := (end - start) / RippleRings
+
+	var out []string
+	for i := 0; i < RippleRings; i++ {
+		size := RippleStepPx * (i + 1)
+		ring := rect{x: max(cx-size/2, 0), y: max(cy-size/2, 0), w: size, h: size}
+		enable := fmt.Sprintf("enable='between(t,%.3f,%.3f)'", start+float64(i)*step, start+float64(i+1)*step)
+		out = append(out, drawbox(ring, color+"@0.8", "2", enable))
+	}
+	return out
+}
+
+// blurRegion blurs the target by overlaying a blurred crop of the frame onto
+// itself. Regions too small for boxblur are blacked out instead.
+func blurRegion(i int, r rect, strength int, enable string) string {
+	radius := min(strength, min(r.w, r.h)/4) // chroma planes are half size
+	if radius < 1 {
+		return drawbox(r, "black", "fill", enable)
+	}
+	return fmt.Sprintf("split=2[blurbase%d][blursrc%d];[blursrc%d]crop=%d:%d:%d:%d,boxblur=%d:2[blurred%d];[blurbase%d][blurred%d]overlay=%d:%d:%s",
+		i, i, i, r.w, r.h, r.x, r.y, radius, i, i, i, r.x, r.y, enable)
+}
+
+func drawbox(r rect, color, thickness, enable string) string {
+	return fmt.Sprintf("drawbox=x=%d:y=%d:w=%d:h=%d:color=%s:t=%s:%s", r.x, r.y, r.w, r.h, color, thickness, enable)
+}
+
+// drawtext renders a label overlay at the style's position
+func drawtext(textFile string, style map[string]interface{}, f frame, enable string) string {
+	size, ok := styleFloat(style, "fontSize")
+	if !ok {
+		size = 14
+	}
+	// Page pixel sizes are unreadable on a full-resolution video
+	fontSize := int(math.Max(size*f.sx, float64(f.height)/30))
+
+	var x, y string
+	switch styleString(style, "position") {
+	case "bottom-center":
+		x, y = "(w-text_w)/2", "h-text_h-h*0.08"
+	case "top-left":
+		x, y = "w*0.04", "h*0.06"
+	case "top-right":
+		x, y = "w-text_w-w*0.04", "h*0.06"
+	default: // top-center
+		x, y = "(w-text_w)/2", "h*0.06"
+	}
+
+	opts := []string{
+		fmt.Sprintf("textfile='%s'", textFile),
+		fmt.Sprintf("fontsize=%d", fontSize),
+		"fontcolor=" + ffColor(styleString(style, "fontColor"), "white"),
+		"box=1",
+		"boxcolor=" + LabelBoxColor,
+		fmt.Sprintf("boxborderw=%d", fontSize/2),
+		"x=" + x,
+		"y=" + y,
+		enable,
+	}
+	if font := os.Getenv("RENDER_FONT_FILE"); font != "" {
+		opts = append([]string{fmt.Sprintf("fontfile='%s'", font)}, opts...)
+	}
+	return "drawtext=" + strings.Join(opts, ":")
+}
+
+func newZoom(e models.DisplayEffect, r rect, scale float64) zoomSpan {
+	return zoomSpan{
+		start: e.Start,
+		end:   e.End,
+		scale: math.Max(1, scale),
+		cx:    float64(r.x) + float64(r.w)/2,
+		cy:    float64(r.y) + float64(r.h)/2,
+	}
+}
+
+// zoompan builds one zoompan filter whose zoom and pan follow every zoom span.
+// Outside the spans zoom is 1, so the frame passes through unchanged.
+func zoompan(zooms []zoomSpan, f frame) string {
+	z, x, y := "1", "0", "0"
+	for i := len(zooms) - 1; i >= 0; i-- {
+		s := zooms[i]
+		cond := fmt.Sprintf("between(in_time,%.3f,%.3f)", s.start, s.end)
+		z = fmt.Sprintf("if(%s,%.3f,%s)", cond, s.scale, z)
+		x = fmt.Sprintf("if(%s,clip(%.1f-iw/zoom/2,0,iw-iw/zoom),%s)", cond, s.cx, x)
+		y = fmt.Sprintf("if(%s,clip(%.1f-ih/zoom/2,0,ih-ih/zoom),%s)", cond, s.cy, y)
+	}
+	return fmt.Sprintf("zoompan=z='%s':x='%s':y='%s':d=1:s=%dx%d:fps=%.3f", z, x, y, f.width, f.height, f.fps)
+}
+
+var hexColor = regexp.MustCompile(`^#?[0-9a-fA-F]{6}([0-9a-fA-F]{2})?$`)
+var namedColor = regexp.MustCompile(`^[a-zA-Z]+(@[0-9.]+)?$`)
+
+// ffColor converts a CSS hex or named color to ffmpeg syntax, ignoring anything
+// that could break out of the filtergraph
+func ffColor(c, def string) string {
+	switch {
+	case hexColor.MatchString(c):
+		return "0x" + strings.TrimPrefix(c, "#")
+	case namedColor.MatchString(c):
+		return c
+	default:
+		return def
+	}
+}
+
+// styleFloat reads a numeric style value; "14px" style strings are accepted
+func styleFloat(style map[string]interface{}, key string) (float64, bool) {
+	switch v := style[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(v, "px"), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func styleBool(style map[string]interface{}, key string) bool {
+	b, _ := style[key].(bool)
+	return b
+}
+
+func styleString(style map[string]interface{}, key string) string {
+	s, _ := style[key].(string)
+	return s
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
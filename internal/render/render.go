@@ -0,0 +1,252 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"godemo/internal/audio"
+	"godemo/internal/models"
+	"godemo/internal/progress"
+)
+
+const (
+	DefaultFPS = 30
+	MaxFPS     = 60 // browser recordings often report a bogus 1000/1 frame rate
+)
+
+// Protocols ffmpeg may open for each kind of input. Without a whitelist a
+// playlist could pull in local files or arbitrary network resources.
+const (
+	localProtocols  = "file"
+	remoteProtocols = "http,https,tls,tcp"
+)
+
+// Dir is where rendered videos are written and served from
+var Dir = filepath.Join("instructions", "renders")
+
+// Request describes one render: the original recording plus the pipeline output
+type Request struct {
+	SessionID      string                 `json:"sessionId"`
+	VideoPath      string                 `json:"videoPath"`           // file under RENDER_RECORDINGS_DIR, or an http(s) URL on RENDER_ALLOWED_HOSTS
+	AudioFile      string                 `json:"audioFile,omitempty"` // "/audio/<session>.mp3" from the pipeline response
+	VideoDuration  float64                `json:"videoDuration,omitempty"`
+	DisplayEffects []models.DisplayEffect `json:"displayEffects,omitempty"`
+	Edits          []models.Edit          `json:"edits,omitempty"` // dead-time edit decision list; effects are timed on its output
+
+	// Page size the effect bounds were measured in; defaults to the video size
+	ViewportWidth  float64 `json:"viewportWidth,omitempty"`
+	ViewportHeight float64 `json:"viewportHeight,omitempty"`
+}
+
+// VideoInfo is the probed geometry of the source recording
+type VideoInfo struct {
+	Width  int
+	Height int
+	FPS    float64
+}
+
+// Render burns the display effects into the recording, muxes in the narration
+// track and writes <session>.mp4 to Dir. It returns the output path.
+func Render(ctx context.Context, req Request) (string, error) {
+	progress.StageStarted(ctx, progress.StageRender)
+
+	outPath, err := render(ctx, req)
+	if err != nil {
+		progress.StageFailed(ctx, progress.StageRender, err)
+		return "", err
+	}
+
+	progress.StageFinished(ctx, progress.StageRender, fmt.Sprintf("%d effects rendered", len(req.DisplayEffects)))
+	return outPath, nil
+}
+
+func render(ctx context.Context, req Request) (string, error) {
+	if models.SessionFileName(req.SessionID) == "" {
+		return "", fmt.Errorf("invalid sessionId %q", req.SessionID)
+	}
+
+	video, err := ResolveVideo(req.VideoPath)
+	if err != nil {
+		return "", err
+	}
+
+	audioPath := ""
+	if name := strings.TrimPrefix(req.AudioFile, "/audio/"); name != "" {
+		audioPath = filepath.Join(audio.Dir, filepath.Base(name))
+		if _, err := os.Stat(audioPath); err != nil {
+			return "", fmt.Errorf("narration audio not found: %v", err)
+		}
+	}
+
+	info, err := ProbeVideo(ctx, video)
+	if err != nil {
+		return "", err
+	}
+
+	// Label text goes through files so it never needs filtergraph escaping
+	textDir, err := os.MkdirTemp("", "render_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(textDir)
+
+	graph, err := buildFilterGraph(req.DisplayEffects, newFrame(info, req.ViewportWidth, req.ViewportHeight), textDir)
+	if err != nil {
+		return "", err
+	}
+	if edit := editFilter(req.Edits, info.FPS); edit != "" {
+		graph = edit + "," + graph
+	}
+
+	if err := os.MkdirAll(Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+	outPath := filepath.Join(Dir, models.SessionFileName(req.SessionID)+".mp4")
+
+	args := append([]string{"-y"}, inputArgs(video)...)
+	if audioPath != "" {
+		args = append(args, "-protocol_whitelist", localProtocols, "-i", audioPath)
+	}
+	args = append(args,
+		"-filter_complex", "[0:v]"+graph+"[v]",
+		"-map", "[v]",
+	)
+	if audioPath != "" {
+		args = append(args, "-map", "1:a", "-c:a", "aac", "-b:a", "192k")
+	} else {
+		// The recording's own audio is the raw voice-over being replaced
+		args = append(args, "-an")
+	}
+	args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p")
+	if req.VideoDuration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", req.VideoDuration))
+	}
+	args = append(args, "-movflags", "+faststart", outPath)
+
+	log.Printf("[RENDER] %s: %dx%d @ %.0ffps, %d effects, %d edits", req.SessionID, info.Width, info.Height, info.FPS, len(req.DisplayEffects), len(req.Edits))
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("ffmpeg error: %v: %s", err, lastLines(stderr.String(), 5))
+	}
+
+	log.Printf("[SUCCESS] Rendered video saved to %s", outPath)
+	return outPath, nil
+}
+
+// ResolveVideo maps a request's video path to an ffmpeg input.
+// Local paths are confined to RENDER_RECORDINGS_DIR (default "recordings").
+// Remote URLs are refused unless their host is listed in RENDER_ALLOWED_HOSTS.
+func ResolveVideo(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("videoPath required")
+	}
+	if isRemote(path) {
+		u, err := url.Parse(path)
+		if err != nil || u.Hostname() == "" {
+			return "", fmt.Errorf("invalid videoPath URL: %q", path)
+		}
+		if !allowedHost(u.Hostname()) {
+			return "", fmt.Errorf("videoPath host not allowed: %q", u.Hostname())
+		}
+		return path, nil
+	}
+
+	root := os.Getenv("RENDER_RECORDINGS_DIR")
+	if root == "" {
+		root = "recordings"
+	}
+
+	rel := filepath.Clean(path)
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("videoPath must be relative to the recordings directory: %q", path)
+	}
+
+	full := filepath.Join(root, rel)
+	if _, err := os.Stat(full); err != nil {
+		return "", fmt.Errorf("recording not found: %v", err)
+	}
+	return full, nil
+}
+
+// allowedHost reports whether host is in the comma-separated RENDER_ALLOWED_HOSTS
+func allowedHost(host string) bool {
+	for _, h := range strings.Split(os.Getenv("RENDER_ALLOWED_HOSTS"), ",") {
+		if h = strings.TrimSpace(h); h != "" && strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRemote(input string) bool {
+	return strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://")
+}
+
+// inputArgs opens a resolved video with only the protocols its kind needs
+func inputArgs(input string) []string {
+	protocols := localProtocols
+	if isRemote(input) {
+		protocols = remoteProtocols
+	}
+	return []string{"-protocol_whitelist", protocols, "-i", input}
+}
+
+// ProbeVideo reads the size and frame rate of the first video stream using ffprobe
+func ProbeVideo(ctx context.Context, input string) (VideoInfo, error) {
+	args := []string{
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height,r_frame_rate",
+		"-of", "csv=p=0",
+	}
+	cmd := exec.CommandContext(ctx, "ffprobe", append(args, inputArgs(input)...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("ffprobe error: %v: %s", err, stderr.String())
+	}
+
+	fields := strings.Split(strings.TrimSpace(string(out)), ",")
+	if len(fields) < 3 {
+		return VideoInfo{}, fmt.Errorf("ffprobe returned no video stream: %q", out)
+	}
+
+	info := VideoInfo{FPS: DefaultFPS}
+	info.Width, _ = strconv.Atoi(fields[0])
+	info.Height, _ = strconv.Atoi(fields[1])
+	if info.Width <= 0 || info.Height <= 0 {
+		return VideoInfo{}, fmt.Errorf("ffprobe returned invalid size: %q", out)
+	}
+
+	if num, den, ok := strings.Cut(fields[2], "/"); ok {
+		n, _ := strconv.ParseFloat(num, 64)
+		d, _ := strconv.ParseFloat(den, 64)
+		if n > 0 && d > 0 && n/d <= MaxFPS {
+			info.FPS = n / d
+		}
+	}
+	return info, nil
+}
+
+// lastLines keeps the tail of ffmpeg's stderr, which is where the actual error is
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
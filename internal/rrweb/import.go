@@ -0,0 +1,346 @@
+package rrweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"godemo/internal/models"
+)
+
+// rrweb event types
+const (
+	eventFullSnapshot        = 2
+	eventIncrementalSnapshot = 3
+	eventMeta                = 4
+)
+
+// Incremental snapshot sources
+const (
+	sourceMutation         = 0
+	sourceMouseMove        = 1
+	sourceMouseInteraction = 2
+	sourceScroll           = 3
+	sourceViewportResize   = 4
+	sourceInput            = 5
+)
+
+// Mouse interaction types worth narrating
+const (
+	interactionClick    = 2
+	interactionDblClick = 4
+)
+
+const (
+	PointerBoxSize    = 48   // rrweb records no layout, so targets are boxed around the pointer
+	InputCollapseMs   = 1500 // rrweb emits one input event per keystroke
+	DefaultSessionTag = "rrweb"
+)
+
+// Recording is an rrweb event stream converted to the pipeline's input shape
+type Recording struct {
+	SessionID string
+	StartTime int64 // absolute ms of the first event
+	Events    []models.DomEvent
+}
+
+type event struct {
+	Type      int             `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// envelope is the optional wrapper the capture extension puts around rrweb events
+type envelope struct {
+	SessionID string  `json:"sessionId"`
+	Events    []event `json:"events"`
+}
+
+// Detect reports whether domRaw holds an rrweb event stream, either as a bare
+// array or as {"sessionId", "events"} with numeric event types
+func Detect(domRaw json.RawMessage) bool {
+	events, _, err := decode(domRaw)
+	if err != nil || len(events) == 0 {
+		return false
+	}
+	for _, e := range events {
+		if e.Type == eventFullSnapshot || e.Type == eventMeta {
+			return true
+		}
+	}
+	return false
+}
+
+func decode(domRaw json.RawMessage) ([]event, string, error) {
+	trimmed := bytes.TrimSpace(domRaw)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []event
+		err := json.Unmarshal(trimmed, &events)
+		return events, "", err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(trimmed, &env); err != nil {
+		return nil, "", err
+	}
+	return env.Events, env.SessionID, nil
+}
+
+// Import converts an rrweb recording into DOM events. Node IDs are resolved to
+// targets using the full snapshot and the mutations replayed since.
+func Import(domRaw json.RawMessage) (*Recording, error) {
+	events, sessionID, err := decode(domRaw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse rrweb events: %v", err)
+	}
+	if len(events) == 0 {
+		return nil, errors.New("rrweb recording has no events")
+	}
+
+	rec := &Recording{SessionID: sessionID, StartTime: events[0].Timestamp}
+	if rec.SessionID == "" {
+		rec.SessionID = DefaultSessionTag + "_" + strconv.FormatInt(rec.StartTime, 10)
+	}
+
+	imp := &importer{dom: newMirror(), pointers: make(map[int]models.BoundingBox)}
+	for _, e := range events {
+		imp.handle(e)
+	}
+	if nav := imp.pendingNav; nav != nil {
+		imp.emit(nav.Type, nav.Timestamp, nav.Target, nil)
+	}
+
+	// Pointer samples are kept apart while importing so they never sit between
+	// keystrokes being folded into one input event
+	rec.Events = append(imp.out, imp.moves...)
+	sort.SliceStable(rec.Events, func(i, j int) bool { return rec.Events[i].Timestamp < rec.Events[j].Timestamp })
+
+	return rec, nil
+}
+
+// importer holds the replay state while walking the event stream
+type importer struct {
+	dom        *mirror
+	href       string
+	viewport   *models.Viewport
+	scroll     *models.ScrollPosition // document scroll offset
+	pointerX   float64
+	pointerY   float64
+	pointers   map[int]models.BoundingBox // last known box per node
+	lastInput  int64                      // timestamp of the latest keystroke
+	pendingNav *models.DomEvent           // navigation waiting for its page's snapshot
+	out        []models.DomEvent
+	moves      []models.DomEvent // mousemove samples
+}
+
+func (imp *importer) handle(e event) {
+	switch e.Type {
+	case eventMeta:
+		var meta struct {
+			Href   string  `json:"href"`
+			Width  float64 `json:"width"`
+			Height float64 `json:"height"`
+		}
+		if json.Unmarshal(e.Data, &meta) != nil {
+			return
+		}
+		if meta.Width > 0 && meta.Height > 0 {
+			imp.viewport = &models.Viewport{Width: meta.Width, Height: meta.Height}
+		}
+		if meta.Href == "" || meta.Href == imp.href {
+			return
+		}
+		from := imp.href
+		imp.href = meta.Href
+		if from != "" {
+			imp.pendingNav = &models.DomEvent{
+				Type:      "navigation",
+				Timestamp: e.Timestamp,
+				Target:    map[string]interface{}{"url": meta.Href, "from": from},
+			}
+		}
+
+	case eventFullSnapshot:
+		var snap struct {
+			Node          serializedNode `json:"node"`
+			InitialOffset struct {
+				Top  float64 `json:"top"`
+				Left float64 `json:"left"`
+			} `json:"initialOffset"`
+		}
+		if json.Unmarshal(e.Data, &snap) == nil {
+			imp.dom.reset(snap.Node)
+			imp.scroll = &models.ScrollPosition{X: snap.InitialOffset.Left, Y: snap.InitialOffset.Top}
+		}
+
+		// The snapshot following a meta event holds the new page's title
+		if nav := imp.pendingNav; nav != nil {
+			imp.pendingNav = nil
+			imp.emit(nav.Type, nav.Timestamp, nav.Target, nil)
+		}
+
+	case eventIncrementalSnapshot:
+		var inc struct {
+			Source int `json:"source"`
+		}
+		if json.Unmarshal(e.Data, &inc) != nil {
+			return
+		}
+		imp.incremental(inc.Source, e)
+	}
+}
+
+func (imp *importer) incremental(source int, e event) {
+	switch source {
+	case sourceMutation:
+		imp.dom.apply(e.Data)
+
+	case sourceMouseMove:
+		var mv struct {
+			Positions []struct {
+				X          float64 `json:"x"`
+				Y          float64 `json:"y"`
+				TimeOffset int64   `json:"timeOffset"` // ms before the event, <= 0
+			} `json:"positions"`
+		}
+		if json.Unmarshal(e.Data, &mv) == nil && len(mv.Positions) > 0 {
+			last := mv.Positions[len(mv.Positions)-1]
+			imp.pointerX, imp.pointerY = last.X, last.Y
+			for _, p := range mv.Positions {
+				imp.moves = append(imp.moves, models.DomEvent{
+					Type:      "mousemove",
+					Timestamp: e.Timestamp + p.TimeOffset,
+					Pointer:   &models.Point{X: p.X, Y: p.Y},
+					Metadata:  &models.EventMetadata{Viewport: imp.viewport},
+				})
+			}
+		}
+
+	case sourceMouseInteraction:
+		var mi struct {
+			Type int      `json:"type"`
+			ID   int      `json:"id"`
+			X    *float64 `json:"x"`
+			Y    *float64 `json:"y"`
+		}
+		if json.Unmarshal(e.Data, &mi) != nil {
+			return
+		}
+		if mi.X != nil && mi.Y != nil {
+			imp.pointerX, imp.pointerY = *mi.X, *mi.Y
+		}
+		if mi.Type != interactionClick && mi.Type != interactionDblClick {
+			return
+		}
+		box := imp.pointerBox(mi.ID)
+		imp.pointers[mi.ID] = *box
+		imp.emit("click", e.Timestamp, imp.dom.target(mi.ID), box)
+
+	case sourceScroll:
+		var sc struct {
+			ID int     `json:"id"`
+			X  float64 `json:"x"`
+			Y  float64 `json:"y"`
+		}
+		if json.Unmarshal(e.Data, &sc) != nil {
+			return
+		}
+		target := imp.dom.target(sc.ID)
+		if n, ok := imp.dom.nodes[sc.ID]; ok && n.typ == nodeDocument {
+			imp.scroll = &models.ScrollPosition{X: sc.X, Y: sc.Y}
+		} else if target != nil {
+			// Scrolling inside an element leaves the page offset alone
+			target["scrollX"], target["scrollY"] = sc.X, sc.Y
+		}
+		imp.emit("scroll", e.Timestamp, target, nil)
+
+	case sourceViewportResize:
+		var vr struct {
+			Width  float64 `json:"width"`
+			Height float64 `json:"height"`
+		}
+		if json.Unmarshal(e.Data, &vr) == nil && vr.Width > 0 && vr.Height > 0 {
+			imp.viewport = &models.Viewport{Width: vr.Width, Height: vr.Height}
+		}
+
+	case sourceInput:
+		var in struct {
+			ID   int    `json:"id"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(e.Data, &in) != nil {
+			return
+		}
+		imp.input(in.ID, in.Text, e.Timestamp)
+	}
+}
+
+// input records typing, folding keystrokes on the same field into one event
+func (imp *importer) input(id int, text string, ts int64) {
+	if n := len(imp.out); n > 0 {
+		last := &imp.out[n-1]
+		if last.Type == "input" && ts-imp.lastInput < InputCollapseMs && last.Target["rrwebId"] == id {
+			// Keep the time typing started; narration describes it from there
+			last.Target["value"] = text
+			imp.lastInput = ts
+			return
+		}
+	}
+	imp.lastInput = ts
+
+	target := imp.dom.target(id)
+	if target == nil {
+		target = map[string]interface{}{"rrwebId": id}
+	}
+	target["value"] = text
+
+	var box *models.BoundingBox
+	if b, ok := imp.pointers[id]; ok {
+		box = &b
+	}
+	imp.emit("input", ts, target, box)
+}
+
+// pointerBox approximates the target's bounds around the current pointer,
+// sized from rrweb's rr_width/rr_height when it recorded them
+func (imp *importer) pointerBox(id int) *models.BoundingBox {
+	w, h := float64(PointerBoxSize), float64(PointerBoxSize)
+	if n, ok := imp.dom.nodes[id]; ok {
+		if v := pixels(n.attrs["rr_width"]); v > 0 {
+			w = v
+		}
+		if v := pixels(n.attrs["rr_height"]); v > 0 {
+			h = v
+		}
+	}
+	return &models.BoundingBox{
+		X:      max(imp.pointerX-w/2, 0),
+		Y:      max(imp.pointerY-h/2, 0),
+		Width:  w,
+		Height: h,
+	}
+}
+
+func (imp *importer) emit(typ string, ts int64, target map[string]interface{}, bounds *models.BoundingBox) {
+	imp.out = append(imp.out, models.DomEvent{
+		Type:      typ,
+		Timestamp: ts,
+		Target:    target,
+		Bounds:    bounds,
+		Metadata: &models.EventMetadata{
+			URL:            imp.href,
+			Title:          imp.dom.title(),
+			Viewport:       imp.viewport,
+			ScrollPosition: imp.scroll,
+		},
+	})
+}
+
+func pixels(s string) float64 {
+	v, _ := strconv.ParseFloat(strings.TrimSuffix(s, "px"), 64)
+	return v
+}
@@ -0,0 +1,267 @@
+package rrweb
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Serialized node types from rrweb-snapshot
+const (
+	nodeDocument = 0
+	nodeElement  = 2
+	nodeText     = 3
+)
+
+const (
+	MaxSelectorDepth = 4   // matches the selectors produced by the capture extension
+	MaxTextLength    = 100 // target text is a hint for labels, not a transcript
+)
+
+// serializedNode is one node of a full snapshot or a mutation add
+type serializedNode struct {
+	Type        int              `json:"type"`
+	ID          int              `json:"id"`
+	TagName     string           `json:"tagName"`
+	Attributes  map[string]any   `json:"attributes"`
+	ChildNodes  []serializedNode `json:"childNodes"`
+	TextContent string           `json:"textContent"`
+}
+
+// node is the mirror of a live DOM node, enough to describe event targets
+type node struct {
+	id       int
+	typ      int
+	tag      string
+	attrs    map[string]string
+	text     string
+	parent   *node
+	children []*node
+}
+
+// mirror tracks the recorded DOM by rrweb node ID
+type mirror struct {
+	nodes map[int]*node
+}
+
+func newMirror() *mirror {
+	return &mirror{nodes: make(map[int]*node)}
+}
+
+// reset replaces the mirror with a full snapshot
+func (m *mirror) reset(root serializedNode) {
+	m.nodes = make(map[int]*node)
+	m.add(nil, root)
+}
+
+// add inserts a serialized subtree under parent
+func (m *mirror) add(parent *node, sn serializedNode) {
+	n := &node{
+		id:     sn.ID,
+		typ:    sn.Type,
+		tag:    strings.ToLower(sn.TagName),
+		attrs:  make(map[string]string),
+		text:   sn.TextContent,
+		parent: parent,
+	}
+	for k, v := range sn.Attributes {
+		if s, ok := v.(string); ok {
+			n.attrs[k] = s
+		}
+	}
+	if parent != nil {
+		parent.children = append(parent.children, n)
+	}
+	m.nodes[n.id] = n
+
+	for _, c := range sn.ChildNodes {
+		m.add(n, c)
+	}
+}
+
+// remove detaches a node and forgets its subtree
+func (m *mirror) remove(id int) {
+	n, ok := m.nodes[id]
+	if !ok {
+		return
+	}
+	if p := n.parent; p != nil {
+		for i, c := range p.children {
+			if c == n {
+				p.children = append(p.children[:i], p.children[i+1:]...)
+				break
+			}
+		}
+	}
+	m.forget(n)
+}
+
+func (m *mirror) forget(n *node) {
+	delete(m.nodes, n.id)
+	for _, c := range n.children {
+		m.forget(c)
+	}
+}
+
+// mutation is the payload of an incremental DOM mutation
+type mutation struct {
+	Adds []struct {
+		ParentID int            `json:"parentId"`
+		Node     serializedNode `json:"node"`
+	} `json:"adds"`
+	Removes []struct {
+		ID int `json:"id"`
+	} `json:"removes"`
+	Texts []struct {
+		ID    int     `json:"id"`
+		Value *string `json:"value"`
+	} `json:"texts"`
+	Attributes []struct {
+		ID         int            `json:"id"`
+		Attributes map[string]any `json:"attributes"`
+	} `json:"attributes"`
+}
+
+// apply replays a mutation so later events resolve against the current DOM
+func (m *mirror) apply(raw json.RawMessage) {
+	var mu mutation
+	if err := json.Unmarshal(raw, &mu); err != nil {
+		return
+	}
+	for _, r := range mu.Removes {
+		m.remove(r.ID)
+	}
+	for _, a := range mu.Adds {
+		if parent, ok := m.nodes[a.ParentID]; ok {
+			m.add(parent, a.Node)
+		}
+	}
+	for _, t := range mu.Texts {
+		if n, ok := m.nodes[t.ID]; ok && t.Value != nil {
+			n.text = *t.Value
+		}
+	}
+	for _, a := range mu.Attributes {
+		n, ok := m.nodes[a.ID]
+		if !ok {
+			continue
+		}
+		for k, v := range a.Attributes {
+			if s, ok := v.(string); ok {
+				n.attrs[k] = s
+			} else {
+				delete(n.attrs, k) // null removes the attribute
+			}
+		}
+	}
+}
+
+// target describes a node in the same shape the capture extension sends
+func (m *mirror) target(id int) map[string]interface{} {
+	n, ok := m.nodes[id]
+	if !ok {
+		return nil
+	}
+	if n.typ == nodeDocument {
+		return map[string]interface{}{"tag": "document", "rrwebId": id}
+	}
+	if n.typ == nodeText && n.parent != nil {
+		n = n.parent
+	}
+
+	t := map[string]interface{}{
+		"tag":        strings.ToUpper(n.tag),
+		"classes":    classes(n),
+		"selector":   selector(n),
+		"attributes": map[string]interface{}{},
+		"rrwebId":    id,
+	}
+	if v := n.attrs["id"]; v != "" {
+		t["id"] = v
+	}
+	if text := textContent(n); text != "" {
+		t["text"] = text
+	}
+
+	attrs := t["attributes"].(map[string]interface{})
+	for _, k := range []string{"aria-label", "name", "placeholder", "href", "type", "role", "title"} {
+		if v := n.attrs[k]; v != "" {
+			attrs[k] = v
+		}
+	}
+	// Keys the effect and instruction generators look for
+	if v := n.attrs["aria-label"]; v != "" {
+		t["ariaLabel"] = v
+	}
+	if v := n.attrs["name"]; v != "" {
+		t["name"] = v
+	}
+	return t
+}
+
+// title returns the document's <title> text, if the snapshot has one
+func (m *mirror) title() string {
+	for _, n := range m.nodes {
+		if n.typ == nodeElement && n.tag == "title" {
+			return strings.TrimSpace(textContent(n))
+		}
+	}
+	return ""
+}
+
+func classes(n *node) []string {
+	return strings.Fields(n.attrs["class"])
+}
+
+// selector builds a short CSS path, stopping early at an element with an id
+func selector(n *node) string {
+	var parts []string
+	for cur := n; cur != nil && cur.typ == nodeElement && len(parts) < MaxSelectorDepth; cur = cur.parent {
+		if id := cur.attrs["id"]; id != "" {
+			parts = append(parts, "#"+id)
+			break
+		}
+		part := cur.tag
+		for _, c := range classes(cur) {
+			part += "." + c
+		}
+		parts = append(parts, part)
+	}
+
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return strings.Join(parts, " > ")
+}
+
+// textContent joins the visible text under n, truncated to MaxTextLength
+func textContent(n *node) string {
+	var b strings.Builder
+	var walk func(*node)
+	walk = func(cur *node) {
+		if b.Len() >= MaxTextLength {
+			return
+		}
+		if cur.typ == nodeText {
+			if s := strings.TrimSpace(cur.text); s != "" {
+				if b.Len() > 0 {
+					b.WriteString("\n")
+				}
+				b.WriteString(s)
+			}
+			return
+		}
+		if cur.tag == "script" || cur.tag == "style" {
+			return
+		}
+		for _, c := range cur.children {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	runes := []rune(b.String())
+	if len(runes) > MaxTextLength {
+		runes = runes[:MaxTextLength]
+	}
+	return string(runes)
+}
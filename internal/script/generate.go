@@ -15,6 +15,46 @@ const (
 	MaxSummaryActions    = 3     // max actions to narrate in one window
 )
 
+// Script modes a request can ask for
+const (
+	ModeLLM           = "llm"           // LLM-refined script (default)
+	ModeDeterministic = "deterministic" // GenerateScript per window, no network
+)
+
+// DefaultMusicStyle backs deterministic narration, which has no LLM to suggest one
+const DefaultMusicStyle = "minimal"
+
+// GenerateNarrations scripts every narration window deterministically.
+// Windows that GenerateScript leaves silent produce no narration, so narrations
+// are numbered by their own position, as the LLM path numbers them.
+func GenerateNarrations(
+	windows []models.Window,
+	timeline []models.TimelineItem,
+) ([]models.Narration, error) {
+
+	var narrations []models.Narration
+
+	for i, w := range windows {
+		text, err := GenerateScript(w, timeline)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %v", i, err)
+		}
+		if text == "" {
+			continue
+		}
+
+		narrations = append(narrations, models.Narration{
+			WindowIndex: len(narrations),
+			Start:       w.Start,
+			End:         w.End,
+			Text:        normalizeSentence(text),
+			MusicStyle:  DefaultMusicStyle,
+		})
+	}
+
+	return narrations, nil
+}
+
 // GenerateScript creates narration text for a single narration window.
 // It is deterministic, action-grounded, and time-safe.
 func GenerateScript(
@@ -122,7 +162,9 @@ func extractSpeechForWindow(
 			continue
 		}
 
-		if item.T < window.Start || item.T > window.End {
+		// Half-open like extractActionsForWindow, so a word on a shared
+		// boundary belongs to the next window only
+		if item.T < window.Start || item.T >= window.End {
 			continue
 		}
 
@@ -201,6 +243,9 @@ func narrateSingleAction(action models.TimelineItem) string {
 		return "The user navigates to a new section."
 
 	case "scroll":
+		if dir := scrollDirection(action); dir != "" {
+			return fmt.Sprintf("The page is scrolled %s to explore more content.", dir)
+		}
 		return "The page is scrolled to explore more content."
 
 	default:
@@ -216,12 +261,23 @@ func narrateMultipleActions(actions []models.TimelineItem) string {
 	}
 
 	if counts["scroll"] > 1 && len(counts) == 1 {
+		if dir := scrollDirection(actions[len(actions)-1]); dir != "" {
+			return fmt.Sprintf("The user scrolls %s through the page to explore more content.", dir)
+		}
 		return "The user scrolls through the page to explore more content."
 	}
 
 	return "Various sections of the interface are explored."
 }
 
+// scrollDirection returns "down", "up", etc. when the scroll moved noticeably
+func scrollDirection(action models.TimelineItem) string {
+	if action.Scroll == nil || action.Scroll.Direction == "none" {
+		return ""
+	}
+	return action.Scroll.Direction
+}
+
 // extractTargetLabel gets a user-friendly label from the target DOM element
 func extractTargetLabel(action models.TimelineItem) string {
 
@@ -1,3 +1,233 @@
 package segment
 
-// Segment compression functions
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"godemo/internal/models"
+)
+
+// Edit actions
+const (
+	EditKeep  = "keep"
+	EditSpeed = "speed"
+	EditCut   = "cut"
+)
+
+const (
+	DefaultMinSpanSec = 2.0 // idle spans shorter than this are left alone
+	DefaultMaxSpanSec = 8.0 // longer idle spans are cut instead of sped up
+	DefaultSpeed      = 4   // playback rate of sped-up spans; whole numbers so frames can be dropped evenly
+	DefaultKeepSec    = 1.0 // idle time kept around a cut so it doesn't jump straight between actions
+
+	WordHoldSec     = 0.6 // timeline words carry only a start time; assume they last this long
+	WordPadSec      = 0.2
+	ActionPadSec    = 0.5 // lead-in before an action
+	ActionSettleSec = 1.5 // time to see the result of an action
+)
+
+// ignoredActions never keep a span alive on their own
+var ignoredActions = map[string]bool{
+	"hover":     true,
+	"mousemove": true,
+	"mouseover": true,
+	"focus":     true,
+	"blur":      true,
+}
+
+// Config controls which idle spans are compressed and how
+type Config struct {
+	MinSpanSec float64
+	MaxSpanSec float64
+	Speed      float64
+	KeepSec    float64
+}
+
+// DefaultConfig returns the built-in compression limits
+func DefaultConfig() Config {
+	return Config{
+		MinSpanSec: DefaultMinSpanSec,
+		MaxSpanSec: DefaultMaxSpanSec,
+		Speed:      DefaultSpeed,
+		KeepSec:    DefaultKeepSec,
+	}
+}
+
+// ConfigFromEnv overrides the defaults with SEGMENT_MIN_SPAN_SEC, SEGMENT_MAX_SPAN_SEC,
+// SEGMENT_SPEED and SEGMENT_KEEP_SEC
+func ConfigFromEnv() Config {
+	cfg := DefaultConfig()
+	if v, err := strconv.ParseFloat(os.Getenv("SEGMENT_MIN_SPAN_SEC"), 64); err == nil && v > 0 {
+		cfg.MinSpanSec = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SEGMENT_MAX_SPAN_SEC"), 64); err == nil && v > 0 {
+		cfg.MaxSpanSec = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SEGMENT_SPEED")); err == nil && v >= 2 {
+		cfg.Speed = float64(v)
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SEGMENT_KEEP_SEC"), 64); err == nil && v >= 0 {
+		cfg.KeepSec = v
+	}
+	cfg.MaxSpanSec = math.Max(cfg.MaxSpanSec, cfg.MinSpanSec)
+	return cfg
+}
+
+// Span is a stretch of the recording
+type Span struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// EDL is an edit decision list covering the whole source recording in order
+type EDL []models.Edit
+
+// IdleSpans finds the stretches of the timeline with no speech and no meaningful
+// action, padded so words and the results of actions stay on screen
+func IdleSpans(tl []models.TimelineItem, videoDuration float64) []Span {
+	var busy []Span
+	for _, item := range tl {
+		switch {
+		case item.Kind == "speech_word":
+			busy = append(busy, Span{item.T - WordPadSec, item.T + WordHoldSec + WordPadSec})
+		case item.Kind == "action" && !ignoredActions[item.Action]:
+			busy = append(busy, Span{item.T - ActionPadSec, item.T + ActionSettleSec})
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool { return busy[i].Start < busy[j].Start })
+
+	var idle []Span
+	cursor := 0.0
+	for _, b := range busy {
+		if b.Start > cursor {
+			idle = append(idle, Span{cursor, math.Min(b.Start, videoDuration)})
+		}
+		cursor = math.Max(cursor, b.End)
+		if cursor >= videoDuration {
+			break
+		}
+	}
+	if cursor < videoDuration {
+		idle = append(idle, Span{cursor, videoDuration})
+	}
+	return idle
+}
+
+// Compress proposes an edit for every idle span: spans between cfg.MinSpanSec and
+// cfg.MaxSpanSec play at cfg.Speed, longer ones are cut down to cfg.KeepSec.
+// Everything else is kept as recorded.
+func Compress(tl []models.TimelineItem, videoDuration float64, cfg Config) EDL {
+	var edl EDL
+	out := 0.0
+	add := func(action string, start, end, speed float64) {
+		if end-start <= 0 {
+			return
+		}
+		length := end - start
+		switch action {
+		case EditSpeed:
+			length /= speed
+		case EditCut:
+			length = 0
+		}
+		if n := len(edl); n > 0 && action == EditKeep && edl[n-1].Action == EditKeep {
+			edl[n-1].SourceEnd = end
+			edl[n-1].OutputEnd += length
+			out += length
+			return
+		}
+		e := models.Edit{Action: action, SourceStart: start, SourceEnd: end, OutputStart: out, OutputEnd: out + length}
+		if action == EditSpeed {
+			e.Speed = speed
+		}
+		edl = append(edl, e)
+		out += length
+	}
+
+	cursor := 0.0
+	for _, s := range IdleSpans(tl, videoDuration) {
+		length := s.End - s.Start
+		if length < cfg.MinSpanSec {
+			continue
+		}
+		add(EditKeep, cursor, s.Start, 1)
+		if length <= cfg.MaxSpanSec {
+			add(EditSpeed, s.Start, s.End, cfg.Speed)
+		} else {
+			half := cfg.KeepSec / 2
+			add(EditKeep, s.Start, s.Start+half, 1)
+			add(EditCut, s.Start+half, s.End-half, 1)
+			add(EditKeep, s.End-half, s.End, 1)
+		}
+		cursor = s.End
+	}
+	add(EditKeep, cursor, videoDuration, 1)
+	return edl
+}
+
+// Duration is the length of the compressed recording
+func (e EDL) Duration() float64 {
+	if len(e) == 0 {
+		return 0
+	}
+	return e[len(e)-1].OutputEnd
+}
+
+// Changed reports whether the list removes or speeds up anything
+func (e EDL) Changed() bool {
+	for _, edit := range e {
+		if edit.Action != EditKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Remap moves a source timestamp onto the compressed timeline. Times inside a
+// cut land on the cut point; times inside a sped-up span move proportionally.
+func (e EDL) Remap(t float64) float64 {
+	for _, edit := range e {
+		if t > edit.SourceEnd {
+			continue
+		}
+		if t <= edit.SourceStart {
+			return edit.OutputStart
+		}
+		switch edit.Action {
+		case EditCut:
+			return edit.OutputStart
+		case EditSpeed:
+			return edit.OutputStart + (t-edit.SourceStart)/edit.Speed
+		default:
+			return edit.OutputStart + (t - edit.SourceStart)
+		}
+	}
+	return e.Duration()
+}
+
+// RemapTimeline returns a copy of the items with their times remapped
+func (e EDL) RemapTimeline(items []models.TimelineItem) []models.TimelineItem {
+	out := make([]models.TimelineItem, len(items))
+	for i, item := range items {
+		item.T = e.Remap(item.T)
+		out[i] = item
+	}
+	return out
+}
+
+// RemapTranscript returns a copy of the transcript with word times remapped
+func (e EDL) RemapTranscript(dg *models.DeepgramResult) *models.DeepgramResult {
+	if dg == nil {
+		return nil
+	}
+	out := *dg
+	out.Words = make([]models.DeepgramWord, len(dg.Words))
+	for i, w := range dg.Words {
+		w.Start = e.Remap(w.Start)
+		w.End = e.Remap(w.End)
+		out.Words[i] = w
+	}
+	return &out
+}
@@ -0,0 +1,304 @@
+package validate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"godemo/internal/duration"
+	"godemo/internal/models"
+)
+
+const (
+	MinBoxSize = 24.0 // px; zero-area boxes with a real position are expanded to this
+)
+
+// Repair actions
+const (
+	RepairClamped    = "clamped"
+	RepairMerged     = "merged"
+	RepairDropped    = "dropped"
+	RepairExpanded   = "expanded"
+	RepairReassigned = "reassigned"
+	RepairShortened  = "shortened"
+)
+
+// Repair records one change made to the raw output so it can be audited
+type Repair struct {
+	Rule    string `json:"rule"`   // validation rule the repair addresses
+	Target  string `json:"target"` // "window" | "narration" | "audio" | "action" | "effect"
+	Index   int    `json:"index"`  // index in the slice before repair
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// Output is the set of pipeline results the repair pass may modify
+type Output struct {
+	VideoDuration float64
+	Windows       []models.Window
+	Narrations    []models.Narration
+	AudioChunks   []models.AudioChunk
+	Actions       []models.ActionInstruction
+	Effects       []models.DisplayEffect
+}
+
+type repairLog []Repair
+
+func (l *repairLog) add(rule, target string, index int, action, format string, args ...interface{}) {
+	*l = append(*l, Repair{
+		Rule:    rule,
+		Target:  target,
+		Index:   index,
+		Action:  action,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// RepairOutput fixes what the validation rules would flag, using the same rules.
+// The input slices are not modified; every change is listed in the returned repairs.
+func RepairOutput(out Output) (Output, []Repair) {
+	var log repairLog
+
+	windows, windowMap := mergeWindows(&log, out.Windows)
+	out.Windows = windows
+	out.Narrations = reassignNarrations(&log, windows, windowMap, out.Narrations)
+	out.AudioChunks = repairAudio(&log, windows, windowMap, out.AudioChunks, out.VideoDuration)
+	out.Actions = repairActions(&log, out.Actions)
+	out.Effects = repairEffects(&log, out.VideoDuration, out.Effects)
+
+	if log == nil {
+		log = repairLog{}
+	}
+	return out, log
+}
+
+// mergeWindows sorts windows and merges any that overlap.
+// The returned map gives the new index of every original window.
+func mergeWindows(log *repairLog, windows []models.Window) ([]models.Window, []int) {
+	order := make([]int, len(windows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return windows[order[a]].Start < windows[order[b]].Start
+	})
+
+	indexMap := make([]int, len(windows))
+	var merged []models.Window
+
+	for _, i := range order {
+		w := windows[i]
+		last := len(merged) - 1
+		if last >= 0 && w.Start < merged[last].End {
+			log.add("window.overlap", "window", i, RepairMerged,
+				"window %d (%.2f–%.2f) merged into %.2f–%.2f", i, w.Start, w.End, merged[last].Start, merged[last].End)
+			merged[last].End = math.Max(merged[last].End, w.End)
+			indexMap[i] = last
+			continue
+		}
+		merged = append(merged, w)
+		indexMap[i] = len(merged) - 1
+	}
+
+	return merged, indexMap
+}
+
+// reassignNarrations follows window merges and moves narrations with invalid window references
+// to the window they overlap most
+func reassignNarrations(
+	log *repairLog,
+	windows []models.Window,
+	windowMap []int,
+	narrations []models.Narration,
+) []models.Narration {
+
+	out := make([]models.Narration, len(narrations))
+	copy(out, narrations)
+
+	for i := range out {
+		n := &out[i]
+		if n.WindowIndex >= 0 && n.WindowIndex < len(windowMap) {
+			n.WindowIndex = windowMap[n.WindowIndex]
+			continue
+		}
+
+		best := bestWindow(windows, n.Start, n.End)
+		if best < 0 {
+			continue
+		}
+		log.add("narration.invalid_window", "narration", i, RepairReassigned,
+			"narration window %d → %d", n.WindowIndex, best)
+		n.WindowIndex = best
+	}
+
+	return out
+}
+
+// repairAudio follows window merges, reassigns invalid references and shortens
+// chunks that would outlive the slot duration fitting gave them. Chunks the
+// fitter already placed inside their slot are left alone.
+func repairAudio(
+	log *repairLog,
+	windows []models.Window,
+	windowMap []int,
+	chunks []models.AudioChunk,
+	videoDuration float64,
+) []models.AudioChunk {
+
+	out := make([]models.AudioChunk, len(chunks))
+	copy(out, chunks)
+
+	for i := range out {
+		a := &out[i]
+		if a.WindowIndex >= 0 && a.WindowIndex < len(windowMap) {
+			a.WindowIndex = windowMap[a.WindowIndex]
+		} else {
+			best := bestWindow(windows, a.Start, a.End)
+			if best < 0 {
+				continue
+			}
+			log.add("audio.invalid_window", "audio", i, RepairReassigned,
+				"audio chunk window %d → %d", a.WindowIndex, best)
+			a.WindowIndex = best
+		}
+
+		slotEnd := fitSlotEnd(out, i, videoDuration)
+		if a.Duration <= 0 || a.Start+a.Duration <= slotEnd+MaxAllowedDriftSec {
+			continue
+		}
+
+		allowed := math.Max(slotEnd-a.Start, 0)
+		log.add("audio.drift", "audio", i, RepairShortened,
+			"audio chunk shortened from %.2fs to %.2fs", a.Duration, allowed)
+
+		// Copy the fit decision so the caller's chunk is untouched; the mixer trims on Trimmed
+		fit := models.FitDecision{Strategy: "trim", RawDuration: a.Duration, SlotDuration: allowed}
+		if a.Fit != nil {
+			fit = *a.Fit
+		}
+		fit.Trimmed = true
+		a.Fit = &fit
+		a.Duration = allowed
+		a.End = math.Min(a.End, slotEnd)
+	}
+
+	return out
+}
+
+// fitSlotEnd is where chunk i must stop. A fitted chunk carries its slot in its
+// fit decision, measured from its planned start; otherwise the slot is computed
+// the way duration.FitChunks does: until just before the next chunk, and the
+// last chunk until the end of the video.
+func fitSlotEnd(chunks []models.AudioChunk, i int, videoDuration float64) float64 {
+	a := chunks[i]
+	if a.Fit != nil && a.Fit.SlotDuration > 0 {
+		return a.Start + a.Fit.ShiftSec + a.Fit.SlotDuration
+	}
+	if i < len(chunks)-1 && chunks[i+1].Start > a.Start {
+		return chunks[i+1].Start - duration.MinGapSec
+	}
+	return videoDuration
+}
+
+// repairActions drops placeholder bounds and expands zero-area ones
+func repairActions(log *repairLog, actions []models.ActionInstruction) []models.ActionInstruction {
+	out := make([]models.ActionInstruction, len(actions))
+	copy(out, actions)
+
+	for i := range out {
+		a := &out[i]
+		if a.Bounds == nil || (a.Bounds.Width > 0 && a.Bounds.Height > 0) {
+			continue
+		}
+
+		box, ok := fixBox(*a.Bounds)
+		if !ok {
+			log.add("action.invalid_bounds", "action", i, RepairDropped, "placeholder bounds removed")
+			a.Bounds = nil
+			continue
+		}
+		log.add("action.invalid_bounds", "action", i, RepairExpanded,
+			"bounds expanded to %.0fx%.0f at (%.0f, %.0f)", box.Width, box.Height, box.X, box.Y)
+		a.Bounds = &box
+	}
+
+	return out
+}
+
+// repairEffects clamps effects into the video and fixes their target geometry.
+// Effects left with no usable time range or target are dropped.
+func repairEffects(log *repairLog, videoDuration float64, effects []models.DisplayEffect) []models.DisplayEffect {
+	var out []models.DisplayEffect
+
+	for i, e := range effects {
+		if e.Start < 0 || e.End > videoDuration {
+			start := math.Max(e.Start, 0)
+			end := math.Min(e.End, videoDuration)
+			log.add("effect.exceeds_duration", "effect", i, RepairClamped,
+				"effect clamped from %.2f–%.2f to %.2f–%.2f", e.Start, e.End, start, end)
+			e.Start, e.End = start, end
+		}
+
+		if e.Start >= e.End {
+			log.add("effect.invalid_range", "effect", i, RepairDropped, "effect has no remaining duration")
+			continue
+		}
+
+		if e.Target != nil && e.Target.Bounds != nil && (e.Target.Bounds.Width <= 0 || e.Target.Bounds.Height <= 0) {
+			box, ok := fixBox(*e.Target.Bounds)
+			if !ok {
+				log.add("effect.invalid_bounds", "effect", i, RepairDropped, "%s effect has only placeholder bounds", e.Type)
+				continue
+			}
+			log.add("effect.invalid_bounds", "effect", i, RepairExpanded,
+				"bounds expanded to %.0fx%.0f at (%.0f, %.0f)", box.Width, box.Height, box.X, box.Y)
+			target := *e.Target
+			target.Bounds = &box
+			e.Target = &target
+		}
+
+		out = append(out, e)
+	}
+
+	return out
+}
+
+// fixBox expands a zero-area box around its position.
+// Boxes at the origin are the recorder's "no bbox" placeholder and cannot be fixed.
+func fixBox(b models.BoundingBox) (models.BoundingBox, bool) {
+	if b.X == 0 && b.Y == 0 {
+		return b, false
+	}
+	if b.Width <= 0 {
+		b.X = math.Max(b.X-MinBoxSize/2, 0)
+		b.Width = MinBoxSize
+	}
+	if b.Height <= 0 {
+		b.Y = math.Max(b.Y-MinBoxSize/2, 0)
+		b.Height = MinBoxSize
+	}
+	return b, true
+}
+
+// bestWindow returns the window overlapping start–end the most, else the nearest one
+func bestWindow(windows []models.Window, start, end float64) int {
+	best, bestOverlap, bestDist := -1, 0.0, math.Inf(1)
+	mid := (start + end) / 2
+
+	for i, w := range windows {
+		overlap := math.Min(end, w.End) - math.Max(start, w.Start)
+		if overlap > bestOverlap {
+			best, bestOverlap = i, overlap
+			continue
+		}
+		if bestOverlap > 0 {
+			continue
+		}
+		dist := math.Min(math.Abs(mid-w.Start), math.Abs(mid-w.End))
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+
+	return best
+}
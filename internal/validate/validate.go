@@ -1,7 +1,6 @@
 package validate
 
 import (
-	"errors"
 	"fmt"
 
 	"godemo/internal/models"
@@ -11,188 +10,237 @@ const (
 	MaxAllowedDriftSec = 0.15 // hard sync tolerance
 )
 
-// ValidateFinalOutput performs final sync and consistency checks before returning to frontend
+// Validation modes
+const (
+	ModeWarn   = "warn"   // default: report violations alongside the response
+	ModeStrict = "strict" // any error-severity violation fails the request
+)
+
+// Severity of a single violation
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Violation is one broken rule on one output item
+type Violation struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Index    int      `json:"index"` // position in the offending slice, -1 for global rules
+	Start    float64  `json:"start"`
+	End      float64  `json:"end,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Report collects every violation found in the final output
+type Report struct {
+	Mode       string      `json:"mode"`
+	Valid      bool        `json:"valid"` // no error-severity violations
+	Errors     int         `json:"errors"`
+	Warnings   int         `json:"warnings"`
+	Violations []Violation `json:"violations"`
+}
+
+func (r *Report) add(sev Severity, rule string, index int, start, end float64, format string, args ...interface{}) {
+	r.Violations = append(r.Violations, Violation{
+		Severity: sev,
+		Rule:     rule,
+		Index:    index,
+		Start:    start,
+		End:      end,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if sev == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// Failed reports whether the report should fail the request in its mode
+func (r *Report) Failed() bool {
+	return r.Mode == ModeStrict && r.Errors > 0
+}
+
+// ValidateFinalOutput performs final sync and consistency checks before returning to frontend.
+// Unlike a fail-fast check it keeps going and records every violation it finds.
 func ValidateFinalOutput(
+	mode string,
 	videoDuration float64,
 	windows []models.Window,
 	narrations []models.Narration,
 	audioChunks []models.AudioChunk,
 	actions []models.ActionInstruction,
 	effects []models.DisplayEffect,
-) error {
-
-	if videoDuration <= 0 {
-		return errors.New("invalid video duration")
-	}
-
-	if err := validateWindows(videoDuration, windows); err != nil {
-		return err
-	}
-
-	if err := validateNarrations(windows, narrations); err != nil {
-		return err
-	}
+) *Report {
 
-	if err := validateAudioSync(windows, audioChunks); err != nil {
-		return err
+	if mode != ModeStrict {
+		mode = ModeWarn
 	}
+	r := &Report{Mode: mode, Violations: []Violation{}}
 
-	if err := validateActions(videoDuration, actions); err != nil {
-		return err
+	if videoDuration <= 0 {
+		r.add(SeverityError, "video.invalid_duration", -1, 0, 0, "invalid video duration %.2f", videoDuration)
+		r.Valid = false
+		return r
 	}
 
-	if err := validateEffects(videoDuration, effects); err != nil {
-		return err
-	}
+	validateWindows(r, videoDuration, windows)
+	validateNarrations(r, windows, narrations)
+	validateAudioSync(r, windows, audioChunks)
+	validateActions(r, videoDuration, actions)
+	validateEffects(r, videoDuration, effects)
 
-	return nil
+	r.Valid = r.Errors == 0
+	return r
 }
 
 // validateWindows ensures narration windows are valid and non-overlapping
-func validateWindows(videoDuration float64, windows []models.Window) error {
+func validateWindows(r *Report, videoDuration float64, windows []models.Window) {
 
 	lastEnd := 0.0
 
 	for i, w := range windows {
 
 		if w.Start < 0 || w.End < 0 {
-			return fmt.Errorf("window %d has negative time", i)
+			r.add(SeverityError, "window.negative_time", i, w.Start, w.End, "window %d has negative time", i)
 		}
 
 		if w.Start >= w.End {
-			return fmt.Errorf("window %d has invalid range", i)
+			r.add(SeverityError, "window.invalid_range", i, w.Start, w.End, "window %d has invalid range", i)
 		}
 
 		if w.End > videoDuration {
-			return fmt.Errorf("window %d exceeds video duration", i)
+			r.add(SeverityError, "window.exceeds_duration", i, w.Start, w.End, "window %d exceeds video duration", i)
 		}
 
 		if w.Start < lastEnd {
-			return fmt.Errorf("window %d overlaps previous window", i)
+			r.add(SeverityError, "window.overlap", i, w.Start, w.End, "window %d overlaps previous window", i)
 		}
 
 		lastEnd = w.End
 	}
-
-	return nil
 }
 
 // validateNarrations ensures narration stays within its assigned window
 func validateNarrations(
+	r *Report,
 	windows []models.Window,
 	narrations []models.Narration,
-) error {
+) {
 
-	for _, n := range narrations {
+	for i, n := range narrations {
 
 		if n.Text == "" {
 			continue // silence is valid
 		}
 
 		if n.WindowIndex < 0 || n.WindowIndex >= len(windows) {
-			return fmt.Errorf("narration references invalid window %d", n.WindowIndex)
+			r.add(SeverityError, "narration.invalid_window", i, n.Start, n.End, "narration references invalid window %d", n.WindowIndex)
+			continue
 		}
 
 		w := windows[n.WindowIndex]
 
 		if n.Start < w.Start || n.End > w.End {
-			return fmt.Errorf(
+			r.add(SeverityWarning, "narration.out_of_window", i, n.Start, n.End,
 				"narration out of window bounds (%.2f–%.2f vs %.2f–%.2f)",
 				n.Start, n.End, w.Start, w.End,
 			)
 		}
 	}
-
-	return nil
 }
 
 // validateAudioSync ensures audio never outlives its narration window
 func validateAudioSync(
+	r *Report,
 	windows []models.Window,
 	audioChunks []models.AudioChunk,
-) error {
+) {
 
-	for _, a := range audioChunks {
+	for i, a := range audioChunks {
 
 		if a.WindowIndex < 0 || a.WindowIndex >= len(windows) {
-			return fmt.Errorf("audio chunk references invalid window %d", a.WindowIndex)
+			r.add(SeverityError, "audio.invalid_window", i, a.Start, a.End, "audio chunk references invalid window %d", a.WindowIndex)
+			continue
 		}
 
 		w := windows[a.WindowIndex]
-		windowDur := w.End - w.Start
 
 		if a.Duration <= 0 {
-			return fmt.Errorf("audio duration must be positive")
+			// Duration is unknown when the chunk could not be probed
+			r.add(SeverityWarning, "audio.unknown_duration", i, a.Start, a.End, "audio duration must be positive")
+			continue
 		}
 
-		if a.Duration > windowDur+MaxAllowedDriftSec {
-			return fmt.Errorf(
-				"audio duration %.2fs exceeds window %.2fs",
-				a.Duration, windowDur,
+		// Duration fitting may start a chunk early in preceding silence,
+		// so what matters is where the audio ends, not how long it is
+		if a.Start+a.Duration > w.End+MaxAllowedDriftSec {
+			r.add(SeverityError, "audio.drift", i, a.Start, a.Start+a.Duration,
+				"audio ending at %.2fs outlives window ending at %.2fs",
+				a.Start+a.Duration, w.End,
 			)
 		}
 	}
-
-	return nil
 }
 
 // validateActions ensures action instructions have valid timestamps and geometry
 func validateActions(
+	r *Report,
 	videoDuration float64,
 	actions []models.ActionInstruction,
-) error {
+) {
 
 	for i, a := range actions {
 
 		if a.Timestamp < 0 || a.Timestamp > videoDuration {
-			return fmt.Errorf("action %d has invalid timestamp %.2f", i, a.Timestamp)
+			r.add(SeverityError, "action.invalid_timestamp", i, a.Timestamp, 0, "action %d has invalid timestamp %.2f", i, a.Timestamp)
 		}
 
 		if a.Action == "" {
-			return fmt.Errorf("action %d has empty action type", i)
+			r.add(SeverityError, "action.empty_type", i, a.Timestamp, 0, "action %d has empty action type", i)
 		}
 
 		// Bounds are optional, but if present must be sane
 		if a.Bounds != nil {
 			if a.Bounds.Width <= 0 || a.Bounds.Height <= 0 {
-				return fmt.Errorf("action %d has invalid bounds", i)
+				r.add(SeverityWarning, "action.invalid_bounds", i, a.Timestamp, 0, "action %d has invalid bounds", i)
 			}
 		}
 	}
-
-	return nil
 }
 
 // validateEffects ensures display effects don't overflow video bounds and have valid geometry
 func validateEffects(
+	r *Report,
 	videoDuration float64,
 	effects []models.DisplayEffect,
-) error {
+) {
 
 	for i, e := range effects {
 
 		if e.Start < 0 || e.End < 0 {
-			return fmt.Errorf("effect %d has negative time", i)
+			r.add(SeverityError, "effect.negative_time", i, e.Start, e.End, "effect %d has negative time", i)
 		}
 
 		if e.Start >= e.End {
-			return fmt.Errorf("effect %d has invalid range", i)
+			r.add(SeverityError, "effect.invalid_range", i, e.Start, e.End, "effect %d has invalid range", i)
 		}
 
 		if e.End > videoDuration {
-			return fmt.Errorf("effect %d exceeds video duration", i)
+			r.add(SeverityError, "effect.exceeds_duration", i, e.Start, e.End, "effect %d exceeds video duration", i)
 		}
 
 		if e.Type == "" {
-			return fmt.Errorf("effect %d missing type", i)
+			r.add(SeverityError, "effect.missing_type", i, e.Start, e.End, "effect %d missing type", i)
 		}
 
 		if e.Target != nil && e.Target.Bounds != nil {
 			if e.Target.Bounds.Width <= 0 || e.Target.Bounds.Height <= 0 {
-				return fmt.Errorf("effect %d has invalid bounds", i)
+				r.add(SeverityError, "effect.invalid_bounds", i, e.Start, e.End, "effect %d has invalid bounds", i)
 			}
 		}
 	}
-
-	return nil
 }